@@ -0,0 +1,84 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+var (
+	invalidAccount  = errors.New("invalid account")
+	invalidTransfer = errors.New("transfer needs two different accounts")
+)
+
+// defaultAccount holds records without an account, e.g. all records of ledgers written before accounts existed.
+const defaultAccount = "default"
+
+// normalizeAccount lowercases an account name, names consist of letters, digits, '-' and '_',
+// the default account is stored as an empty name.
+func normalizeAccount(account string) (string, error) {
+	normalized := strings.ToLower(strings.TrimSpace(account))
+	if normalized == "" || normalized == defaultAccount {
+		return "", nil
+	}
+	for _, r := range normalized {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' {
+			return "", fmt.Errorf("%w %q", invalidAccount, account)
+		}
+	}
+	return normalized, nil
+}
+
+// accountName returns the account of the record for display.
+func accountName(record TrackerRecord) string {
+	if record.Account == "" {
+		return defaultAccount
+	}
+	return record.Account
+}
+
+type AccountBalance struct {
+	Account      string
+	Income       uint
+	Expenses     uint
+	TransfersIn  uint
+	TransfersOut uint
+}
+
+// Balance is money received and transferred in minus money spent and transferred out.
+func (b AccountBalance) Balance() int64 {
+	return int64(b.Income) + int64(b.TransfersIn) - int64(b.Expenses) - int64(b.TransfersOut)
+}
+
+// ComputeAccountBalances sums records per account, sorted by account name.
+func ComputeAccountBalances(records []TrackerRecord) []AccountBalance {
+	balances := make(map[string]AccountBalance)
+	for _, record := range records {
+		account := accountName(record)
+		balance := balances[account]
+		balance.Account = account
+		switch record.Kind {
+		case KindIncome:
+			balance.Income += record.Amount
+		case KindTransferIn:
+			balance.TransfersIn += record.Amount
+		case KindTransferOut:
+			balance.TransfersOut += record.Amount
+		default:
+			balance.Expenses += record.Amount
+		}
+		balances[account] = balance
+	}
+
+	result := make([]AccountBalance, 0, len(balances))
+	for _, balance := range balances {
+		result = append(result, balance)
+	}
+	slices.SortFunc(result, func(a, b AccountBalance) int {
+		return cmp.Compare(a.Account, b.Account)
+	})
+	return result
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeAccount(t *testing.T) {
+	tests := []struct {
+		account string
+		want    string
+		wantErr error
+	}{
+		{account: "", want: ""},
+		{account: "default", want: ""},
+		{account: " Checking ", want: "checking"},
+		{account: "credit-card", want: "credit-card"},
+		{account: "credit card", wantErr: invalidAccount},
+		{account: "cash,", wantErr: invalidAccount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.account, func(t *testing.T) {
+			got, err := normalizeAccount(tt.account)
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("normalizeAccount(%q) = %q, %v, want %q, %v", tt.account, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestComputeAccountBalances(t *testing.T) {
+	tests := []struct {
+		name    string
+		records []TrackerRecord
+		want    []AccountBalance
+	}{
+		{name: "Empty", want: []AccountBalance{}},
+		{
+			name: "SortedByAccount",
+			records: []TrackerRecord{
+				{Amount: 20},
+				{Amount: 1000, Kind: KindIncome, Account: "checking"},
+				{Amount: 100, Kind: KindTransferOut, Account: "checking"},
+				{Amount: 100, Kind: KindTransferIn, Account: "cash"},
+				{Amount: 30, Account: "cash"},
+			},
+			want: []AccountBalance{
+				{Account: "cash", Expenses: 30, TransfersIn: 100},
+				{Account: "checking", Income: 1000, TransfersOut: 100},
+				{Account: "default", Expenses: 20},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ComputeAccountBalances(tt.records)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ComputeAccountBalances() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccountBalance(t *testing.T) {
+	balance := AccountBalance{Income: 1000, Expenses: 300, TransfersIn: 50, TransfersOut: 900}
+	if got := balance.Balance(); got != -150 {
+		t.Errorf("Balance() = %d, want -150", got)
+	}
+}
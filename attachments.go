@@ -0,0 +1,149 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	attachmentNotFound = errors.New("attachment not found")
+	invalidAttachment  = errors.New("invalid attachment name")
+)
+
+// AttachmentStore keeps content of attachments, records refer to content by key.
+type AttachmentStore interface {
+	// Put stores the content and returns its key, storing the same content again returns the same key.
+	Put(content io.Reader) (string, error)
+	Open(key string) (io.ReadCloser, error)
+	Delete(key string) error
+	// Keys lists keys of all stored content.
+	Keys() ([]string, error)
+}
+
+// Attachment is a file linked to a record, files with the same content share the key.
+type Attachment struct {
+	Name string
+	Key  string
+}
+
+// validAttachmentName reports whether name can be stored in the ledger: a file name without path and line breaks.
+func validAttachmentName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, "/\\\r\n")
+}
+
+// formatAttachments encodes attachments for the ledger csv, one "key name" pair per line.
+func formatAttachments(attachments []Attachment) string {
+	lines := make([]string, 0, len(attachments))
+	for _, attachment := range attachments {
+		lines = append(lines, attachment.Key+" "+attachment.Name)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func parseAttachments(value string) ([]Attachment, error) {
+	if value == "" {
+		return nil, nil
+	}
+	lines := strings.Split(value, "\n")
+	attachments := make([]Attachment, 0, len(lines))
+	for _, line := range lines {
+		key, name, ok := strings.Cut(line, " ")
+		if !ok || !validAttachmentKey(key) || !validAttachmentName(name) {
+			return nil, fmt.Errorf("%w %q", invalidAttachment, line)
+		}
+		attachments = append(attachments, Attachment{Name: name, Key: key})
+	}
+	return attachments, nil
+}
+
+// DirAttachmentStore keeps attachments in a directory, files are named by the SHA-256 of their content.
+type DirAttachmentStore struct {
+	dir string
+}
+
+func NewDirAttachmentStore(dir string) *DirAttachmentStore {
+	return &DirAttachmentStore{dir: dir}
+}
+
+func (s *DirAttachmentStore) Put(content io.Reader) (string, error) {
+	err := os.MkdirAll(s.dir, 0700)
+	if err != nil {
+		return "", err
+	}
+	file, err := os.CreateTemp(s.dir, ".tmp*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(file.Name())
+
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(file, hash), content)
+	if err == nil {
+		err = file.Sync()
+	}
+	closeErr := file.Close()
+	if err != nil {
+		return "", err
+	}
+	if closeErr != nil {
+		return "", closeErr
+	}
+
+	key := hex.EncodeToString(hash.Sum(nil))
+	return key, os.Rename(file.Name(), filepath.Join(s.dir, key))
+}
+
+func (s *DirAttachmentStore) Open(key string) (io.ReadCloser, error) {
+	if !validAttachmentKey(key) {
+		return nil, attachmentNotFound
+	}
+	file, err := os.Open(filepath.Join(s.dir, key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, attachmentNotFound
+	}
+	return file, err
+}
+
+func (s *DirAttachmentStore) Delete(key string) error {
+	if !validAttachmentKey(key) {
+		return attachmentNotFound
+	}
+	err := os.Remove(filepath.Join(s.dir, key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *DirAttachmentStore) Keys() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return make([]string, 0), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && validAttachmentKey(entry.Name()) {
+			keys = append(keys, entry.Name())
+		}
+	}
+	return keys, nil
+}
+
+// validAttachmentKey reports whether key is a lowercase hex SHA-256, keys are used as file names.
+func validAttachmentKey(key string) bool {
+	if len(key) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(key)
+	return err == nil && strings.ToLower(key) == key
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDirAttachmentStore(t *testing.T) {
+	store := NewDirAttachmentStore(t.TempDir() + "/attachments")
+
+	keys, err := store.Keys()
+	if err != nil || len(keys) != 0 {
+		t.Fatalf("Keys() of missing directory = %v, %v, want no keys", keys, err)
+	}
+
+	key, err := store.Put(strings.NewReader("receipt"))
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if !validAttachmentKey(key) {
+		t.Errorf("Put() key = %q, want SHA-256 hex", key)
+	}
+	again, err := store.Put(strings.NewReader("receipt"))
+	if err != nil || again != key {
+		t.Errorf("Put() same content = %q, %v, want %q", again, err, key)
+	}
+	other, err := store.Put(strings.NewReader("invoice"))
+	if err != nil || other == key {
+		t.Errorf("Put() other content = %q, %v, want a different key", other, err)
+	}
+
+	content, err := store.Open(key)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	data, err := io.ReadAll(content)
+	content.Close()
+	if err != nil || string(data) != "receipt" {
+		t.Errorf("Open() content = %q, %v, want %q", data, err, "receipt")
+	}
+
+	keys, err = store.Keys()
+	want := []string{key, other}
+	slices.Sort(want)
+	if err != nil || !reflect.DeepEqual(keys, want) {
+		t.Errorf("Keys() = %v, %v, want %v", keys, err, want)
+	}
+
+	if err := store.Delete(key); err != nil {
+		t.Errorf("Delete() error = %v", err)
+	}
+	if err := store.Delete(key); err != nil {
+		t.Errorf("Delete() of deleted key error = %v, want nil", err)
+	}
+	if _, err := store.Open(key); !errors.Is(err, attachmentNotFound) {
+		t.Errorf("Open() of deleted key error = %v, want %v", err, attachmentNotFound)
+	}
+	if _, err := store.Open("../ledger.csv"); !errors.Is(err, attachmentNotFound) {
+		t.Errorf("Open() of invalid key error = %v, want %v", err, attachmentNotFound)
+	}
+}
+
+func TestParseAttachments(t *testing.T) {
+	key := strings.Repeat("ab", 32)
+	tests := []struct {
+		name    string
+		value   string
+		want    []Attachment
+		wantErr bool
+	}{
+		{name: "Empty", value: ""},
+		{name: "One", value: key + " receipt.pdf", want: []Attachment{{Name: "receipt.pdf", Key: key}}},
+		{
+			name:  "NameWithSpaces",
+			value: key + " hotel receipt.pdf\n" + key + " copy.pdf",
+			want:  []Attachment{{Name: "hotel receipt.pdf", Key: key}, {Name: "copy.pdf", Key: key}},
+		},
+		{name: "MissingName", value: key, wantErr: true},
+		{name: "InvalidKey", value: "abc receipt.pdf", wantErr: true},
+		{name: "UpperCaseKey", value: strings.ToUpper(key) + " receipt.pdf", wantErr: true},
+		{name: "NameWithPath", value: key + " ../receipt.pdf", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseAttachments(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseAttachments() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseAttachments() = %v, want %v", got, tt.want)
+			}
+			if !tt.wantErr && formatAttachments(got) != tt.value {
+				t.Errorf("formatAttachments() = %q, want %q", formatAttachments(got), tt.value)
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+var backupNotFound = errors.New("backup not found")
+
+// backupTimeLayout is the timestamp part of backup names, backups of expenses.csv are named like
+// expenses.csv-20240315T120000.000Z.
+const backupTimeLayout = "20060102T150405.000Z"
+
+// BackupRetention sets how many backups are kept: the most recent ones
+// plus the newest backup of each of the last days and months.
+type BackupRetention struct {
+	Recent  int
+	Daily   int
+	Monthly int
+}
+
+var defaultBackupRetention = BackupRetention{Recent: 10, Daily: 7, Monthly: 12}
+
+var backupDescription = fmt.Sprintf("list backups taken before every change of the ledger or restore the ledger from a backup, the current ledger is backed up before restore.\n"+
+	"Backups are kept in %s: the last %d, the newest of each of the last %d days and of the last %d months, set %s=off to disable them",
+	defaultBackupDir, defaultBackupRetention.Recent, defaultBackupRetention.Daily, defaultBackupRetention.Monthly, backupsVariable)
+
+type Backup struct {
+	Name string
+	// ledger file name the backup was taken from
+	Ledger string
+	Time   time.Time
+	Size   int64
+}
+
+// Backups snapshots ledger files into a directory and prunes old snapshots by retention.
+type Backups struct {
+	dir       string
+	retention BackupRetention
+	clock     Clock
+}
+
+func NewBackups(dir string, retention BackupRetention, clock Clock) *Backups {
+	return &Backups{dir: dir, retention: retention, clock: clock}
+}
+
+// Snapshot copies the current content of filename into the backups directory,
+// missing or empty file is not an error as there is nothing to back up yet.
+func (b *Backups) Snapshot(filename string) error {
+	source, err := os.Open(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	info, err := source.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() == 0 {
+		return nil
+	}
+
+	err = os.MkdirAll(b.dir, 0700)
+	if err != nil {
+		return err
+	}
+
+	// names have millisecond precision, move to the next free millisecond on collision
+	stamp := b.clock().UTC().Truncate(time.Millisecond)
+	ledger := filepath.Base(filename)
+	var target *os.File
+	for {
+		target, err = os.OpenFile(filepath.Join(b.dir, backupName(ledger, stamp)), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if !errors.Is(err, fs.ErrExist) {
+			break
+		}
+		stamp = stamp.Add(time.Millisecond)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(target, source)
+	closeErr := target.Close()
+	if err != nil {
+		return err
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+	return b.prune(ledger)
+}
+
+// List returns backups of all ledgers, newest first.
+func (b *Backups) List() ([]Backup, error) {
+	entries, err := os.ReadDir(b.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return make([]Backup, 0), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	backups := make([]Backup, 0, len(entries))
+	for _, entry := range entries {
+		backup, ok := parseBackupName(entry.Name())
+		if !ok || !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		backup.Size = info.Size()
+		backups = append(backups, backup)
+	}
+	slices.SortFunc(backups, func(a, b Backup) int {
+		return b.Time.Compare(a.Time)
+	})
+	return backups, nil
+}
+
+// Read returns content of the backup.
+func (b *Backups) Read(name string) ([]byte, error) {
+	_, ok := parseBackupName(name)
+	if !ok || name != filepath.Base(name) {
+		return nil, backupNotFound
+	}
+	data, err := os.ReadFile(filepath.Join(b.dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, backupNotFound
+	}
+	return data, err
+}
+
+// Restore replaces the ledger in dir with the backup, the current ledger is backed up first.
+func (b *Backups) Restore(name, dir string) (string, error) {
+	data, err := b.Read(name)
+	if err != nil {
+		return "", err
+	}
+	backup, _ := parseBackupName(name)
+
+	ledger := filepath.Join(dir, backup.Ledger)
+	err = b.Snapshot(ledger)
+	if err != nil {
+		return "", fmt.Errorf("backing up current ledger: %w", err)
+	}
+	return ledger, writeFileAtomic(ledger, data, 0600)
+}
+
+// Convert replaces backups of the ledger with backups of newLedger taken at the same time, content is changed by convert,
+// e.g. backups of a plaintext ledger are encrypted when the ledger is. It returns the number of converted backups.
+func (b *Backups) Convert(ledger, newLedger string, convert func(data []byte) ([]byte, error)) (int, error) {
+	backups, err := b.List()
+	if err != nil {
+		return 0, err
+	}
+
+	converted := 0
+	for _, backup := range backups {
+		if backup.Ledger != ledger {
+			continue
+		}
+		data, err := b.Read(backup.Name)
+		if err != nil {
+			return converted, err
+		}
+		data, err = convert(data)
+		if err != nil {
+			return converted, err
+		}
+		err = writeFileAtomic(filepath.Join(b.dir, backupName(newLedger, backup.Time)), data, 0600)
+		if err != nil {
+			return converted, err
+		}
+		err = os.Remove(filepath.Join(b.dir, backup.Name))
+		if err != nil {
+			return converted, err
+		}
+		converted++
+	}
+	return converted, b.prune(newLedger)
+}
+
+// prune removes backups of the ledger not kept by retention.
+func (b *Backups) prune(ledger string) error {
+	backups, err := b.List()
+	if err != nil {
+		return err
+	}
+	backups = slices.DeleteFunc(backups, func(backup Backup) bool {
+		return backup.Ledger != ledger
+	})
+
+	keep := backupsToKeep(backups, b.retention)
+	for _, backup := range backups {
+		if keep[backup.Name] {
+			continue
+		}
+		err := os.Remove(filepath.Join(b.dir, backup.Name))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backupsToKeep selects names of backups kept by retention from backups sorted newest first,
+// days and months are in local time.
+func backupsToKeep(backups []Backup, retention BackupRetention) map[string]bool {
+	keep := make(map[string]bool)
+	for i := 0; i < retention.Recent && i < len(backups); i++ {
+		keep[backups[i].Name] = true
+	}
+	keepNewestPer := func(layout string, count int) {
+		seen := make(map[string]bool)
+		for _, backup := range backups {
+			if len(seen) == count {
+				return
+			}
+			key := backup.Time.Local().Format(layout)
+			if !seen[key] {
+				seen[key] = true
+				keep[backup.Name] = true
+			}
+		}
+	}
+	keepNewestPer(time.DateOnly, retention.Daily)
+	keepNewestPer("2006-01", retention.Monthly)
+	return keep
+}
+
+func backupName(ledger string, stamp time.Time) string {
+	return ledger + "-" + stamp.Format(backupTimeLayout)
+}
+
+func parseBackupName(name string) (Backup, bool) {
+	separator := strings.LastIndex(name, "-")
+	if separator <= 0 {
+		return Backup{}, false
+	}
+	stamp, err := time.Parse(backupTimeLayout, name[separator+1:])
+	if err != nil {
+		return Backup{}, false
+	}
+	return Backup{Name: name, Ledger: name[:separator], Time: stamp}, true
+}
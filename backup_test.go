@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBackupsToKeep(t *testing.T) {
+	// newest first, one backup every 12 hours going back 90 days
+	var backups []Backup
+	start := time.Date(2024, 3, 15, 18, 0, 0, 0, time.Local)
+	for i := 0; i < 180; i++ {
+		stamp := start.Add(-time.Duration(i) * 12 * time.Hour)
+		backups = append(backups, Backup{Name: stamp.Format(time.DateTime), Time: stamp})
+	}
+
+	tests := []struct {
+		name      string
+		retention BackupRetention
+		want      []string
+	}{
+		{name: "Nothing", retention: BackupRetention{}, want: []string{}},
+		{name: "Recent", retention: BackupRetention{Recent: 3}, want: []string{"2024-03-15 18:00:00", "2024-03-15 06:00:00", "2024-03-14 18:00:00"}},
+		{name: "Daily", retention: BackupRetention{Daily: 2}, want: []string{"2024-03-15 18:00:00", "2024-03-14 18:00:00"}},
+		{
+			name:      "Monthly",
+			retention: BackupRetention{Monthly: 4},
+			want:      []string{"2024-03-15 18:00:00", "2024-02-29 18:00:00", "2024-01-31 18:00:00", "2023-12-31 18:00:00"},
+		},
+		{
+			name:      "Combined",
+			retention: BackupRetention{Recent: 2, Daily: 2, Monthly: 2},
+			want:      []string{"2024-03-15 18:00:00", "2024-03-15 06:00:00", "2024-03-14 18:00:00", "2024-02-29 18:00:00"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keep := backupsToKeep(backups, tt.retention)
+			got := make([]string, 0)
+			for _, backup := range backups {
+				if keep[backup.Name] {
+					got = append(got, backup.Name)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("backupsToKeep() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBackupName(t *testing.T) {
+	tests := []struct {
+		name       string
+		wantLedger string
+		wantTime   time.Time
+		wantOk     bool
+	}{
+		{name: "expenses.csv-20240315T120000.000Z", wantLedger: "expenses.csv", wantTime: time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC), wantOk: true},
+		{name: "my-expenses.csv.enc-20240315T120000.123Z", wantLedger: "my-expenses.csv.enc", wantTime: time.Date(2024, 3, 15, 12, 0, 0, 123e6, time.UTC), wantOk: true},
+		{name: "expenses.csv", wantOk: false},
+		{name: "expenses.csv-latest", wantOk: false},
+		{name: "-20240315T120000.000Z", wantOk: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseBackupName(tt.name)
+			if ok != tt.wantOk {
+				t.Fatalf("parseBackupName() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if ok && (got.Ledger != tt.wantLedger || !got.Time.Equal(tt.wantTime)) {
+				t.Errorf("parseBackupName() = %+v, want ledger %q at %v", got, tt.wantLedger, tt.wantTime)
+			}
+		})
+	}
+}
+
+func TestBackupsSnapshotAndRestore(t *testing.T) {
+	dir := t.TempDir()
+	ledger := filepath.Join(dir, "expenses.csv")
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	backups := NewBackups(filepath.Join(dir, "backups"), BackupRetention{Recent: 2}, func() time.Time {
+		return now
+	})
+
+	err := backups.Snapshot(ledger)
+	if err != nil {
+		t.Fatalf("Snapshot() of missing ledger error = %v", err)
+	}
+
+	storage := NewStorageFromFile(ledger)
+	storage.SetBackups(backups)
+	for i := 1; i <= 4; i++ {
+		err := storage.Save(testRecords()[:min(i, 3)])
+		if err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	list, err := backups.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	// three saves over an existing file, two kept, same clock so names move to the next millisecond
+	wantNames := []string{"expenses.csv-20240315T120000.002Z", "expenses.csv-20240315T120000.001Z"}
+	gotNames := []string{}
+	for _, backup := range list {
+		gotNames = append(gotNames, backup.Name)
+	}
+	if !reflect.DeepEqual(gotNames, wantNames) {
+		t.Fatalf("List() = %v, want %v", gotNames, wantNames)
+	}
+
+	// the oldest kept backup was taken before the third save, when the ledger had two records
+	restored, err := backups.Restore(list[1].Name, dir)
+	if err != nil || restored != ledger {
+		t.Fatalf("Restore() = %q, %v, want %q", restored, err, ledger)
+	}
+	records, err := storage.ReadAll()
+	if err != nil || len(records) != 2 {
+		t.Errorf("restored ledger = %v, %v, want 2 records", records, err)
+	}
+
+	_, err = backups.Restore("expenses.csv-20000101T000000.000Z", dir)
+	if !errors.Is(err, backupNotFound) {
+		t.Errorf("Restore() of missing backup error = %v, want %v", err, backupNotFound)
+	}
+	_, err = backups.Restore("../expenses.csv-20240315T120000.002Z", dir)
+	if !errors.Is(err, backupNotFound) {
+		t.Errorf("Restore() of path outside backups error = %v, want %v", err, backupNotFound)
+	}
+	if _, err := os.Stat(ledger); err != nil {
+		t.Errorf("ledger is missing after failed restore: %v", err)
+	}
+}
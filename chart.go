@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+	// partial blocks from one to seven eighths of a character cell
+	barBlocks = []rune("▏▎▍▌▋▊▉")
+)
+
+type ChartBar struct {
+	Label string
+	Value uint
+}
+
+// RenderBarChart draws a horizontal bar per value with the label on the left and the value on the right,
+// bars are scaled so that the whole line including label and value fits into width characters.
+func RenderBarChart(w io.Writer, bars []ChartBar, width int) error {
+	labelWidth, valueWidth := 0, 0
+	var maxValue uint = 0
+	for _, bar := range bars {
+		labelWidth = max(labelWidth, utf8.RuneCountInString(bar.Label))
+		valueWidth = max(valueWidth, len(strconv.FormatUint(uint64(bar.Value), 10)))
+		maxValue = max(maxValue, bar.Value)
+	}
+
+	// label, " │", bar, " ", value
+	barWidth := max(width-labelWidth-valueWidth-3, 1)
+
+	for _, bar := range bars {
+		label := bar.Label + strings.Repeat(" ", labelWidth-utf8.RuneCountInString(bar.Label))
+		line := barLine(bar.Value, maxValue, barWidth)
+		_, err := fmt.Fprintf(w, "%s │%s %*d\n", label, line, valueWidth, bar.Value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func barLine(value, maxValue uint, width int) string {
+	eighths := 0
+	if maxValue > 0 {
+		eighths = int(uint64(value) * uint64(width) * 8 / uint64(maxValue))
+	}
+	line := strings.Repeat("█", eighths/8)
+	length := eighths / 8
+	if eighths%8 > 0 {
+		line += string(barBlocks[eighths%8-1])
+		length++
+	}
+	return line + strings.Repeat(" ", width-length)
+}
+
+// Sparkline renders each value as a single block character, the block height is proportional to the value.
+func Sparkline(values []uint) string {
+	var maxValue uint = 0
+	for _, value := range values {
+		maxValue = max(maxValue, value)
+	}
+
+	var builder strings.Builder
+	for _, value := range values {
+		level := 0
+		if maxValue > 0 {
+			level = int(uint64(value) * uint64(len(sparkBlocks)-1) / uint64(maxValue))
+		}
+		builder.WriteRune(sparkBlocks[level])
+	}
+	return builder.String()
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRenderBarChart(t *testing.T) {
+	tests := []struct {
+		name     string
+		bars     []ChartBar
+		width    int
+		expected string
+	}{
+		{
+			name:     "NoBars",
+			width:    20,
+			expected: "",
+		},
+		{
+			name:  "ScaledToWidth",
+			bars:  []ChartBar{{Label: "Jan", Value: 100}, {Label: "Feb", Value: 50}, {Label: "March", Value: 0}},
+			width: 20,
+			// 20 - 5 (label) - 3 (value) - 3 (separators) = 9 cells for the bar
+			expected: "Jan   │█████████ 100\n" +
+				"Feb   │████▌      50\n" +
+				"March │            0\n",
+		},
+		{
+			name:  "PartialBlocks",
+			bars:  []ChartBar{{Label: "a", Value: 8}, {Label: "b", Value: 1}, {Label: "c", Value: 3}},
+			width: 6,
+			expected: "a │█ 8\n" +
+				"b │▏ 1\n" +
+				"c │▍ 3\n",
+		},
+		{
+			name:     "AllZero",
+			bars:     []ChartBar{{Label: "a", Value: 0}},
+			width:    10,
+			expected: "a │      0\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := RenderBarChart(&buf, tt.bars, tt.width); err != nil {
+				t.Fatalf("RenderBarChart() error = %v", err)
+			}
+			if got := buf.String(); got != tt.expected {
+				t.Errorf("RenderBarChart() =\n%s\nwant\n%s", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []uint
+		want   string
+	}{
+		{name: "Empty", values: nil, want: ""},
+		{name: "AllZero", values: []uint{0, 0, 0}, want: "▁▁▁"},
+		{name: "Ascending", values: []uint{0, 1, 2, 3, 4, 5, 6, 7}, want: "▁▂▃▄▅▆▇█"},
+		{name: "Scaled", values: []uint{100, 50, 0, 1000}, want: "▁▁▁█"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sparkline(tt.values); got != tt.want {
+				t.Errorf("Sparkline() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTerminalWidth(t *testing.T) {
+	tests := []struct {
+		name    string
+		columns string
+		want    int
+	}{
+		{name: "Columns", columns: "120", want: 120},
+		{name: "InvalidColumns", columns: "wide", want: 80},
+		{name: "NotTerminal", columns: "", want: 80},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("COLUMNS", tt.columns)
+			if got := terminalWidth(&bytes.Buffer{}); got != tt.want {
+				t.Errorf("terminalWidth() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,834 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// runCli runs the command line against storage with the clock fixed at 2024-03-15 12:00 local time.
+func runCli(t *testing.T, storage TrackerStorage, stdin string, args ...string) (code int, stdout, stderr string) {
+	t.Helper()
+	return runCliAt(t, time.Date(2024, 3, 15, 12, 0, 0, 0, time.Local), storage, stdin, args...)
+}
+
+func runCliAt(t *testing.T, now time.Time, storage TrackerStorage, stdin string, args ...string) (code int, stdout, stderr string) {
+	t.Helper()
+	var out, errOut strings.Builder
+	env := &Env{
+		Stdin:    strings.NewReader(stdin),
+		Stdout:   &out,
+		Stderr:   &errOut,
+		Now:      FixedClock(now),
+		Location: time.Local,
+		Passphrase: func(_ string) (string, error) {
+			return "secret", nil
+		},
+		OpenStorage: func() (TrackerStorage, error) {
+			return storage, nil
+		},
+	}
+	err := Run(args, env)
+	return ExitCode(err), out.String(), errOut.String()
+}
+
+// flagsTestEnv is an environment for defining flags of commands in tests of usage and completions.
+func flagsTestEnv() *Env {
+	return &Env{
+		Stdin:    strings.NewReader(""),
+		Stdout:   io.Discard,
+		Stderr:   io.Discard,
+		Now:      FixedClock(time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)),
+		Location: time.UTC,
+	}
+}
+
+func TestCli(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		stdin        string
+		wantCode     int
+		wantStdout   string
+		wantContains []string
+		wantStderr   string
+		wantRecords  int
+	}{
+		{name: "NoArgs", wantContains: []string{"Usage: expense-tracker <command>", "expense-tracker list [--tag <tag>]"}},
+		{name: "HelpFlag", args: []string{"--help"}, wantContains: []string{"Usage: expense-tracker <command>"}},
+		{name: "Help", args: []string{"help"}, wantContains: []string{"expense-tracker add --description"}},
+		{name: "HelpCommand", args: []string{"help", "ls"}, wantContains: []string{"Usage: expense-tracker list [--tag <tag>]", "Aliases: ls"}},
+		{name: "HelpDefaultsFromClock", args: []string{"help", "summary"}, wantContains: []string{"(1-12) (default 3)", "quarter (default 2024)"}},
+		{name: "HelpUnknownCommand", args: []string{"help", "lst"}, wantCode: 2, wantStderr: `unknown command "lst", did you mean "list"?`},
+		{name: "UnknownCommand", args: []string{"sumary"}, wantCode: 2, wantStderr: `unknown command "sumary", did you mean "summary"?`},
+		{name: "UnknownCommandWithoutSuggestion", args: []string{"xyz"}, wantCode: 2, wantStderr: `unknown command "xyz"` + "\n"},
+
+		{name: "Add", args: []string{"add", "--description", "Tea", "--amount", "3"}, wantStdout: "Expense added successfully (ID: 4)", wantRecords: 4},
+		{name: "AddWithoutAmount", args: []string{"add", "--description", "Tea"}, wantCode: 2, wantStderr: "Usage: expense-tracker add", wantRecords: 3},
+		{name: "AddWithoutDescription", args: []string{"add", "--amount", "3"}, wantCode: 2, wantStderr: "Usage: expense-tracker add", wantRecords: 3},
+		{name: "AddInvalidFlag", args: []string{"add", "--amount", "-3"}, wantCode: 2, wantStderr: "invalid value", wantRecords: 3},
+		{name: "AddHelp", args: []string{"add", "--help"}, wantStderr: "Usage: expense-tracker add", wantRecords: 3},
+		{name: "Update", args: []string{"update", "--id", "1", "--amount", "25"}, wantStdout: "Record updated successfully (ID: 1)", wantRecords: 3},
+		{name: "UpdateAlias", args: []string{"edit", "--id", "2", "--description", "Supper"}, wantStdout: "Record updated successfully (ID: 2)", wantRecords: 3},
+		{name: "UpdateNotFound", args: []string{"update", "--id", "9", "--amount", "25"}, wantCode: 2, wantStderr: "error updating record: record not found", wantRecords: 3},
+		{name: "UpdateWithoutChanges", args: []string{"update", "--id", "1"}, wantCode: 2, wantStderr: "Usage: expense-tracker update", wantRecords: 3},
+		{name: "Delete", args: []string{"delete", "--id", "2"}, wantStdout: "Record deleted successfully (ID: 2)", wantRecords: 2},
+		{name: "DeleteAlias", args: []string{"rm", "--id", "2"}, wantStdout: "Record deleted successfully (ID: 2)", wantRecords: 2},
+		{name: "DeleteWithoutId", args: []string{"delete"}, wantCode: 2, wantStderr: "Usage: expense-tracker delete", wantRecords: 3},
+		{name: "DeleteInvalidId", args: []string{"delete", "--id", "abc"}, wantCode: 2, wantStderr: "Usage: expense-tracker delete", wantRecords: 3},
+		{name: "DeleteUnknownUid", args: []string{"delete", "--id", "01ARYZ6S41TSV4RRFFQ69G5FAV"}, wantCode: 2, wantStderr: "error deleting record: record not found", wantRecords: 3},
+
+		{
+			name:       "List",
+			args:       []string{"list"},
+			wantStdout: "ID\tDate\t\tDescription\t\tAmount\n1\t2024-01-15\tLunch\t20\n2\t2024-02-15\tDinner\t30\n3\t2024-03-14\tCoffee\t5\n",
+		},
+		{name: "ListAlias", args: []string{"ls"}, wantContains: []string{"3\t2024-03-14\tCoffee\t5\n"}},
+		{name: "Summary", args: []string{"summary"}, wantStdout: "Total expenses: 55"},
+		{name: "SummaryMonthOfCurrentYear", args: []string{"summary", "--month", "2"}, wantStdout: "Total expenses: 30"},
+		{name: "SummaryLast", args: []string{"summary", "--last", "30d"}, wantStdout: "Total expenses: 35"},
+		{name: "SummaryCurrentWeek", args: []string{"summary", "--week"}, wantStdout: "Total expenses: 5"},
+		{name: "SummaryInvalidWeek", args: []string{"summary", "--week", "first"}, wantCode: 2, wantStderr: "invalid week"},
+		{name: "SummaryInvalidMonth", args: []string{"summary", "--month", "13"}, wantCode: 2, wantStderr: "Usage: expense-tracker summary"},
+		{name: "SummaryConflictingFlags", args: []string{"summary", "--month", "2", "--last", "30d"}, wantCode: 2, wantStderr: "Usage: expense-tracker summary"},
+		{name: "Report", args: []string{"report"}, wantContains: []string{"2024-01     20      1", "2024-02     30      1", "Total     55      3"}},
+		{name: "ReportInvalidGranularity", args: []string{"report", "--by", "hour"}, wantCode: 2, wantStderr: "Usage: expense-tracker report"},
+		{name: "Chart", args: []string{"chart", "--width", "40"}, wantContains: []string{"2024-02 │████", "30\n"}},
+		{name: "ChartInvalidWidth", args: []string{"chart", "--width", "5"}, wantCode: 2, wantStderr: "Usage: expense-tracker chart"},
+		{name: "Stats", args: []string{"stats", "--top", "1"}, wantContains: []string{"Count:", "55", "Largest expenses:", "Dinner"}},
+		{name: "StatsInvalidTop", args: []string{"stats", "--top", "-1"}, wantCode: 2, wantStderr: "Usage: expense-tracker stats"},
+
+		{name: "ExportCsv", args: []string{"export", "--output", "-", "--month", "1"}, wantStdout: "Id,Date,Amount,Description,Kind,Account,Payee,Notes,Tags\n1,2024-01-15,20,Lunch,expense,default,,,\n"},
+		{name: "ExportJson", args: []string{"export", "--output", "-", "--format", "json"}, wantContains: []string{`"description": "Lunch"`, `"description": "Coffee"`}},
+		{name: "ExportWithoutOutput", args: []string{"export"}, wantCode: 2, wantStderr: "Usage: expense-tracker export"},
+		{name: "ExportInvalidFormat", args: []string{"export", "--output", "-", "--format", "pdf"}, wantCode: 2, wantStderr: "Usage: expense-tracker export"},
+		{name: "ExportInvalidDelimiter", args: []string{"export", "--output", "-", "--delimiter", ";;"}, wantCode: 2, wantStderr: "Usage: expense-tracker export"},
+
+		{name: "ServeInvalidAddress", args: []string{"serve", "--addr", "invalid:address:1"}, wantCode: 2, wantStderr: "error serving"},
+		{name: "Shell", args: []string{"shell"}, stdin: "summary\nadd --description 'Green tea' --amount 4\nexit\n", wantContains: []string{"Total expenses: 55", "Expense added successfully (ID: 4)"}, wantRecords: 4},
+		{name: "ShellErrors", args: []string{"shell"}, stdin: "lst\nshell\n", wantStderr: "did you mean \"list\"?", wantRecords: 3},
+		{name: "TuiWithoutTerminal", args: []string{"tui"}, wantCode: 2},
+		{name: "Completion", args: []string{"completion", "fish"}, wantContains: []string{"complete -c expense-tracker"}},
+		{name: "CompletionWithoutShell", args: []string{"completion"}, wantCode: 2, wantStderr: "Usage: expense-tracker completion"},
+		{name: "CompleteIds", args: []string{completeCommandName, "ids"}, wantStdout: "1\tLunch\n2\tDinner\n3\tCoffee\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &FakeStorage{records: testRecords()}
+			code, stdout, stderr := runCli(t, storage, tt.stdin, tt.args...)
+
+			if code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d\nstderr: %s", code, tt.wantCode, stderr)
+			}
+			if tt.wantStdout != "" && stdout != tt.wantStdout {
+				t.Errorf("stdout = %q, want %q", stdout, tt.wantStdout)
+			}
+			for _, want := range tt.wantContains {
+				if !strings.Contains(stdout, want) {
+					t.Errorf("stdout does not contain %q:\n%s", want, stdout)
+				}
+			}
+			if tt.wantStderr != "" && !strings.Contains(stderr, tt.wantStderr) {
+				t.Errorf("stderr does not contain %q:\n%s", tt.wantStderr, stderr)
+			}
+			if tt.wantRecords != 0 && len(storage.records) != tt.wantRecords {
+				t.Errorf("stored %d records, want %d", len(storage.records), tt.wantRecords)
+			}
+		})
+	}
+}
+
+func TestCliStorageErrors(t *testing.T) {
+	code, _, stderr := runCli(t, &FakeStorage{readError: errors.New("broken file")}, "", "list")
+	if code != 2 || !strings.Contains(stderr, "Error creating tracker: broken file") {
+		t.Errorf("read error: exit code = %d, stderr = %q", code, stderr)
+	}
+
+	code, _, stderr = runCli(t, &FakeStorage{records: testRecords(), saveError: errors.New("disk full")}, "", "add", "--description", "Tea", "--amount", "3")
+	if code != 2 || !strings.Contains(stderr, "error adding record: disk full") {
+		t.Errorf("save error: exit code = %d, stderr = %q", code, stderr)
+	}
+}
+
+func TestCliClockEdges(t *testing.T) {
+	records := []TrackerRecord{
+		{Id: 1, Description: "Party", Amount: 100, CreatedAt: time.Date(2024, 12, 31, 23, 30, 0, 0, time.Local)},
+		{Id: 2, Description: "Breakfast", Amount: 10, CreatedAt: time.Date(2025, 1, 1, 0, 30, 0, 0, time.Local)},
+	}
+
+	tests := []struct {
+		name       string
+		now        time.Time
+		args       []string
+		wantStdout string
+		wantLines  []string
+		// creation time of the last stored record
+		wantCreatedAt time.Time
+	}{
+		{
+			name:          "AddUsesClock",
+			now:           time.Date(2025, 1, 31, 23, 59, 59, 0, time.Local),
+			args:          []string{"add", "--description", "Late snack", "--amount", "7"},
+			wantStdout:    "Expense added successfully (ID: 3)",
+			wantCreatedAt: time.Date(2025, 1, 31, 23, 59, 59, 0, time.Local),
+		},
+		{
+			name:       "MonthDefaultsToCurrentYearAfterNewYear",
+			now:        time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local),
+			args:       []string{"summary", "--month", "12"},
+			wantStdout: "Total expenses: 0",
+		},
+		{
+			name:       "MonthOfPreviousYear",
+			now:        time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local),
+			args:       []string{"summary", "--month", "12", "--year", "2024"},
+			wantStdout: "Total expenses: 100",
+		},
+		{
+			name:       "LastDayIncludesToday",
+			now:        time.Date(2025, 1, 1, 8, 0, 0, 0, time.Local),
+			args:       []string{"summary", "--last", "1d"},
+			wantStdout: "Total expenses: 10",
+		},
+		{
+			name:      "WeekSpanningNewYear",
+			now:       time.Date(2025, 1, 1, 12, 0, 0, 0, time.Local),
+			args:      []string{"report", "--by", "day"},
+			wantLines: []string{"2024-12-30", "2024-12-31 Tue    100", "2025-01-01 Wed     10", "2025-01-05"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &FakeStorage{records: records}
+			code, stdout, stderr := runCliAt(t, tt.now, storage, "", tt.args...)
+			if code != 0 {
+				t.Fatalf("exit code = %d, stderr: %s", code, stderr)
+			}
+			if tt.wantStdout != "" && stdout != tt.wantStdout {
+				t.Errorf("stdout = %q, want %q", stdout, tt.wantStdout)
+			}
+			for _, want := range tt.wantLines {
+				if !strings.Contains(stdout, want) {
+					t.Errorf("stdout does not contain %q:\n%s", want, stdout)
+				}
+			}
+			if !tt.wantCreatedAt.IsZero() {
+				added := storage.records[len(storage.records)-1]
+				if !added.CreatedAt.Equal(tt.wantCreatedAt) {
+					t.Errorf("CreatedAt = %v, want %v", added.CreatedAt, tt.wantCreatedAt)
+				}
+			}
+		})
+	}
+}
+
+func TestCliReportingLocation(t *testing.T) {
+	tokyo := time.FixedZone("UTC+9", 9*60*60)
+	// 2024-02-01 08:30 in Tokyo
+	records := []TrackerRecord{{Id: 1, Description: "Late dinner", Amount: 40, CreatedAt: time.Date(2024, 1, 31, 23, 30, 0, 0, time.UTC)}}
+	tests := []struct {
+		name       string
+		location   *time.Location
+		args       []string
+		wantStdout string
+	}{
+		{name: "ListUtc", location: time.UTC, args: []string{"list"}, wantStdout: "ID\tDate\t\tDescription\t\tAmount\n1\t2024-01-31\tLate dinner\t40\n"},
+		{name: "ListTokyo", location: tokyo, args: []string{"list"}, wantStdout: "ID\tDate\t\tDescription\t\tAmount\n1\t2024-02-01\tLate dinner\t40\n"},
+		{name: "SummaryUtc", location: time.UTC, args: []string{"summary", "--month", "1", "--year", "2024"}, wantStdout: "Total expenses: 40"},
+		{name: "SummaryTokyo", location: tokyo, args: []string{"summary", "--month", "2", "--year", "2024"}, wantStdout: "Total expenses: 40"},
+		{name: "ExportTokyo", location: tokyo, args: []string{"export", "--output", "-"}, wantStdout: "Id,Date,Amount,Description,Kind,Account,Payee,Notes,Tags\n1,2024-02-01,40,Late dinner,expense,default,,,\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out, errOut strings.Builder
+			env := &Env{
+				Stdin:       strings.NewReader(""),
+				Stdout:      &out,
+				Stderr:      &errOut,
+				Now:         FixedClock(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)),
+				Location:    tt.location,
+				OpenStorage: func() (TrackerStorage, error) { return &FakeStorage{records: records}, nil },
+			}
+			err := Run(tt.args, env)
+			if err != nil {
+				t.Fatalf("Run() error = %v, stderr: %s", err, errOut.String())
+			}
+			if out.String() != tt.wantStdout {
+				t.Errorf("stdout = %q, want %q", out.String(), tt.wantStdout)
+			}
+		})
+	}
+}
+
+func TestCliEncryptDecrypt(t *testing.T) {
+	useFastScrypt(t)
+	dir := t.TempDir()
+	plaintext := filepath.Join(dir, "expenses.csv")
+	encrypted := filepath.Join(dir, "expenses.csv.enc")
+	err := NewStorageFromFile(plaintext).Save(testRecords())
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	code, stdout, stderr := runCli(t, &FakeStorage{}, "", "encrypt", "--input", plaintext)
+	if code != 0 || stdout != "Encrypted 3 records to "+encrypted {
+		t.Fatalf("encrypt: exit code = %d, stdout = %q, stderr = %q", code, stdout, stderr)
+	}
+	if _, err := os.Stat(plaintext); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("encrypt: plaintext file is not removed, error = %v", err)
+	}
+
+	code, _, _ = runCli(t, &FakeStorage{}, "", "encrypt", "--input", encrypted, "--output", filepath.Join(dir, "twice.enc"))
+	if code != 2 {
+		t.Errorf("encrypt of encrypted file: exit code = %d, want 2", code)
+	}
+
+	code, stdout, stderr = runCli(t, &FakeStorage{}, "", "decrypt", "--input", encrypted, "--output", plaintext, "--keep")
+	if code != 0 || stdout != "Decrypted 3 records to "+plaintext {
+		t.Fatalf("decrypt: exit code = %d, stdout = %q, stderr = %q", code, stdout, stderr)
+	}
+	if _, err := os.Stat(encrypted); err != nil {
+		t.Errorf("decrypt --keep: encrypted file is removed, error = %v", err)
+	}
+	records, err := NewStorageFromFile(plaintext).ReadAll()
+	if err != nil || len(records) != 3 || records[0].Description != "Lunch" {
+		t.Errorf("decrypted records = %v, %v", records, err)
+	}
+
+	code, _, _ = runCli(t, &FakeStorage{}, "", "decrypt", "--input", encrypted, "--output", plaintext)
+	if code != 2 {
+		t.Errorf("decrypt over existing file: exit code = %d, want 2", code)
+	}
+}
+
+func TestCliCompleteEncryptedLedger(t *testing.T) {
+	useFastScrypt(t)
+	dir := t.TempDir()
+	plaintext := filepath.Join(dir, "expenses.csv")
+	encrypted := filepath.Join(dir, "expenses.csv.enc")
+	if err := NewEncryptedStorage(encrypted, "secret").Save(testRecords()); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	run := func() (int, string) {
+		var out, errOut strings.Builder
+		env := &Env{
+			Stdin:    strings.NewReader(""),
+			Stdout:   &out,
+			Stderr:   &errOut,
+			Now:      FixedClock(time.Date(2024, 3, 15, 12, 0, 0, 0, time.Local)),
+			Location: time.Local,
+			Passphrase: func(_ string) (string, error) {
+				t.Error("completion asked for the passphrase")
+				return "secret", nil
+			},
+		}
+		env.OpenStorage = func() (TrackerStorage, error) {
+			return openLedger(plaintext, encrypted, env.Passphrase, nil)
+		}
+		return ExitCode(Run([]string{completeCommandName, "ids"}, env)), out.String()
+	}
+
+	// restored on cleanup by Setenv
+	t.Setenv(passphraseVariable, "")
+	os.Unsetenv(passphraseVariable)
+	if code, stdout := run(); code != 2 || stdout != "" {
+		t.Errorf("complete without passphrase variable = %d, %q, want no IDs", code, stdout)
+	}
+	t.Setenv(passphraseVariable, "secret")
+	if code, stdout := run(); code != 0 || !strings.HasPrefix(stdout, "1\tLunch\n") {
+		t.Errorf("complete with passphrase variable = %d, %q, want IDs", code, stdout)
+	}
+}
+
+// chdirTemp changes the working directory to a new temporary directory until the end of the test,
+// for commands working with the ledger in the working directory.
+func chdirTemp(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd() error = %v", err)
+	}
+	err = os.Chdir(dir)
+	if err != nil {
+		t.Fatalf("Chdir() error = %v", err)
+	}
+	t.Cleanup(func() {
+		os.Chdir(wd)
+	})
+	return dir
+}
+
+func TestCliBackup(t *testing.T) {
+	// restore writes the ledger in the working directory
+	dir := chdirTemp(t)
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	backups := NewBackups(filepath.Join(dir, "backups"), defaultBackupRetention, FixedClock(now))
+	storage := NewStorageFromFile(defaultStorageFile)
+	storage.SetBackups(backups)
+
+	run := func(args ...string) (int, string, string) {
+		var out, errOut strings.Builder
+		env := &Env{
+			Stdin:       strings.NewReader(""),
+			Stdout:      &out,
+			Stderr:      &errOut,
+			Now:         FixedClock(now),
+			Location:    time.UTC,
+			Backups:     backups,
+			OpenStorage: func() (TrackerStorage, error) { return storage, nil },
+			Passphrase: func(_ string) (string, error) {
+				return "secret", nil
+			},
+		}
+		err := Run(args, env)
+		return ExitCode(err), out.String(), errOut.String()
+	}
+
+	code, stdout, _ := run("backup", "list")
+	if code != 0 || stdout != "No backups" {
+		t.Errorf("backup list without backups: exit code = %d, stdout = %q", code, stdout)
+	}
+
+	run("add", "--description", "Lunch", "--amount", "20")
+	run("add", "--description", "Dinner", "--amount", "30")
+	code, stdout, _ = run("backup", "list")
+	want := "Name                               Ledger        Date                 Size\n" +
+		"expenses.csv-20240315T120000.000Z  expenses.csv  2024-03-15 12:00:00  95\n"
+	if code != 0 || stdout != want {
+		t.Errorf("backup list: exit code = %d, stdout = %q, want %q", code, stdout, want)
+	}
+
+	code, stdout, stderr := run("backup", "restore", "expenses.csv-20240315T120000.000Z")
+	if code != 0 || stdout != "Restored expenses.csv from expenses.csv-20240315T120000.000Z" {
+		t.Fatalf("backup restore: exit code = %d, stdout = %q, stderr = %q", code, stdout, stderr)
+	}
+	code, stdout, _ = run("summary")
+	if code != 0 || stdout != "Total expenses: 20" {
+		t.Errorf("summary after restore: exit code = %d, stdout = %q", code, stdout)
+	}
+
+	tests := [][]string{
+		{"backup"},
+		{"backup", "remove"},
+		{"backup", "restore"},
+		{"backup", "restore", "missing"},
+	}
+	for _, args := range tests {
+		if code, _, _ := run(args...); code != 2 {
+			t.Errorf("%v: exit code = %d, want 2", args, code)
+		}
+	}
+
+	// encrypting the ledger encrypts its backups, plaintext copies would defeat encryption
+	useFastScrypt(t)
+	code, stdout, stderr = run("encrypt")
+	if code != 0 || stdout != "Encrypted 1 records to ./expenses.csv.enc\nEncrypted 2 backups" {
+		t.Fatalf("encrypt: exit code = %d, stdout = %q, stderr = %q", code, stdout, stderr)
+	}
+	list, err := backups.List()
+	if err != nil || len(list) != 2 {
+		t.Fatalf("backups after encrypt = %v, %v, want 2", list, err)
+	}
+	for _, backup := range list {
+		data, err := backups.Read(backup.Name)
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+		if backup.Ledger != "expenses.csv.enc" {
+			t.Errorf("backup %s is not of the encrypted ledger", backup.Name)
+		}
+		if _, _, _, err := decryptLedger(data, "secret"); err != nil {
+			t.Errorf("backup %s does not decrypt: %v", backup.Name, err)
+		}
+	}
+
+	// restoring a plaintext backup next to the encrypted ledger would make both unreadable
+	plaintextBackup := filepath.Join(dir, "backups", "expenses.csv-20240101T000000.000Z")
+	if err := os.WriteFile(plaintextBackup, []byte("Id,CreatedAt,Amount,Description\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	code, _, stderr = run("backup", "restore", "expenses.csv-20240101T000000.000Z")
+	if code != 2 || !strings.Contains(stderr, "while ./expenses.csv.enc exists") {
+		t.Errorf("restore of plaintext backup: exit code = %d, stderr = %q, want refused", code, stderr)
+	}
+	if _, err := os.Stat(defaultStorageFile); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("plaintext ledger exists after refused restore, error = %v", err)
+	}
+}
+
+// runShellInDir runs the shell against the ledger in the working directory, as the default environment does.
+func runShellInDir(t *testing.T, stdin string) (code int, stdout, stderr string) {
+	t.Helper()
+	var out, errOut strings.Builder
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	env := &Env{
+		Stdin:    strings.NewReader(stdin),
+		Stdout:   &out,
+		Stderr:   &errOut,
+		Now:      FixedClock(now),
+		Location: time.UTC,
+		Backups:  NewBackups(defaultBackupDir, defaultBackupRetention, FixedClock(now)),
+		Passphrase: func(_ string) (string, error) {
+			return "secret", nil
+		},
+	}
+	env.OpenStorage = func() (TrackerStorage, error) {
+		return openLedger(defaultStorageFile, defaultEncryptedFile, env.Passphrase, env.Backups)
+	}
+	err := Run([]string{"shell"}, env)
+	return ExitCode(err), out.String(), errOut.String()
+}
+
+func TestCliShellRestore(t *testing.T) {
+	chdirTemp(t)
+	stdin := "add --description Lunch --amount 20\n" +
+		"add --description Dinner --amount 30\n" +
+		"backup restore expenses.csv-20240315T120000.000Z\n" +
+		"add --description Coffee --amount 5\n"
+	code, _, stderr := runShellInDir(t, stdin)
+	if code != 0 || stderr != "" {
+		t.Fatalf("shell: exit code = %d, stderr = %q", code, stderr)
+	}
+
+	records, err := NewStorageFromFile(defaultStorageFile).ReadAll()
+	if err != nil || len(records) != 2 || records[0].Description != "Lunch" || records[1].Description != "Coffee" {
+		t.Errorf("records after restore in shell = %v, %v, want Lunch and Coffee", records, err)
+	}
+}
+
+func TestCliShellEncrypt(t *testing.T) {
+	useFastScrypt(t)
+	chdirTemp(t)
+	stdin := "add --description Lunch --amount 20\nencrypt\nadd --description Dinner --amount 30\n"
+	code, _, stderr := runShellInDir(t, stdin)
+	if code != 0 || stderr != "" {
+		t.Fatalf("shell: exit code = %d, stderr = %q", code, stderr)
+	}
+
+	if _, err := os.Stat(defaultStorageFile); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("plaintext ledger exists after encrypt in shell, error = %v", err)
+	}
+	records, err := NewEncryptedStorage(defaultEncryptedFile, "secret").ReadAll()
+	if err != nil || len(records) != 2 {
+		t.Errorf("encrypted records = %v, %v, want 2", records, err)
+	}
+}
+
+func TestCliExportFile(t *testing.T) {
+	dir := t.TempDir()
+	wantCsv := "Id,Date,Amount,Description,Kind,Account,Payee,Notes,Tags\n1,2024-01-15,20,Lunch,expense,default,,,\n"
+	for _, name := range []string{"report.txt", "report.CSV"} {
+		output := filepath.Join(dir, name)
+		code, _, stderr := runCli(t, &FakeStorage{records: testRecords()}, "", "export", "--output", output, "--month", "1")
+		if code != 0 {
+			t.Fatalf("export to %s: exit code = %d, stderr = %q", name, code, stderr)
+		}
+		content, err := os.ReadFile(output)
+		if err != nil || string(content) != wantCsv {
+			t.Errorf("export to %s = %q, %v, want csv %q", name, content, err, wantCsv)
+		}
+	}
+}
+
+func TestCliDoctor(t *testing.T) {
+	dir := t.TempDir()
+	ledger := filepath.Join(dir, "expenses.csv")
+	quarantine := ledger + ".quarantine"
+	data := "Id,CreatedAt,Amount,Description\n2,2024-01-02T00:00:00Z,30,Dinner\n1,2024-01-01T00:00:00Z,20,Lunch\n3,2024-01-03T00:00:00Z,abc,Coffee\n"
+	err := os.WriteFile(ledger, []byte(data), 0600)
+	if err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	code, stdout, _ := runCli(t, &FakeStorage{}, "", "doctor", "--file", ledger)
+	wantStdout := "line 3: warning: ID 1 is not greater than previous ID 2\n" +
+		"line 4: error: invalid amount \"abc\"\n" +
+		"Found 1 errors and 1 warnings in " + ledger + "\n" +
+		"Run doctor --repair to move bad rows to the quarantine file"
+	if code != 2 || stdout != wantStdout {
+		t.Errorf("doctor: exit code = %d, stdout = %q, want 2, %q", code, stdout, wantStdout)
+	}
+
+	code, stdout, stderr := runCli(t, &FakeStorage{}, "", "doctor", "--file", ledger, "--repair")
+	if code != 0 || !strings.HasSuffix(stdout, "Repaired "+ledger+": kept 2 records, moved 1 rows to "+quarantine) {
+		t.Fatalf("doctor --repair: exit code = %d, stdout = %q, stderr = %q", code, stdout, stderr)
+	}
+	rows, err := os.ReadFile(quarantine)
+	if err != nil || string(rows) != "3,2024-01-03T00:00:00Z,abc,Coffee\n" {
+		t.Errorf("quarantine file = %q, %v", rows, err)
+	}
+
+	code, stdout, _ = runCli(t, &FakeStorage{}, "", "doctor", "--file", ledger)
+	if code != 0 || stdout != "No issues found in 2 records of "+ledger {
+		t.Errorf("doctor after repair: exit code = %d, stdout = %q", code, stdout)
+	}
+
+	// the quarantined row keeps its ID assigned
+	code, stdout, _ = runCli(t, NewStorageFromFile(ledger), "", "add", "--description", "Coffee", "--amount", "5")
+	if code != 0 || stdout != "Expense added successfully (ID: 4)" {
+		t.Errorf("add after repair: exit code = %d, stdout = %q, want ID 4", code, stdout)
+	}
+
+	code, _, _ = runCli(t, &FakeStorage{}, "", "doctor", "--file", filepath.Join(dir, "missing.csv"))
+	if code != 2 {
+		t.Errorf("doctor of missing file: exit code = %d, want 2", code)
+	}
+}
+
+func TestCliLenientLedger(t *testing.T) {
+	ledger := filepath.Join(t.TempDir(), "expenses.csv")
+	data := "Id,CreatedAt,Amount,Description\n1,2024-01-01T00:00:00Z,20,Lunch\n2,2024-01-02T00:00:00Z,abc,Dinner\n"
+	err := os.WriteFile(ledger, []byte(data), 0600)
+	if err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	storage := NewStorageFromFile(ledger)
+	storage.SetLenient(true)
+	wantWarnings := "warning: line 3 skipped: invalid amount \"abc\"\n" +
+		"warning: ledger is read-only, run expense-tracker doctor --repair to fix it\n"
+
+	code, stdout, stderr := runCli(t, storage, "", "summary")
+	if code != 0 || stdout != "Total expenses: 20" || stderr != wantWarnings {
+		t.Errorf("summary: exit code = %d, stdout = %q, stderr = %q", code, stdout, stderr)
+	}
+
+	code, _, stderr = runCli(t, storage, "", "add", "--description", "Coffee", "--amount", "5")
+	if code != 2 || !strings.HasSuffix(stderr, "error adding record: "+readOnlyLedger.Error()+"\n") {
+		t.Errorf("add: exit code = %d, stderr = %q, want read-only error", code, stderr)
+	}
+}
+
+func TestCliTags(t *testing.T) {
+	storage := &FakeStorage{records: testRecords()}
+	steps := []struct {
+		args       []string
+		wantCode   int
+		wantStdout string
+	}{
+		{args: []string{"tags"}, wantStdout: "No tags"},
+		{args: []string{"add", "--description", "Hotel", "--amount", "100", "--tag", "business", "--tag", "#Trip-Lisbon"}, wantStdout: "Expense added successfully (ID: 4)"},
+		{args: []string{"add", "--description", "Taxi", "--amount", "15", "--tag", "business,taxi"}, wantStdout: "Expense added successfully (ID: 5)"},
+		{args: []string{"add", "--description", "Tram", "--amount", "3", "--tag", "trip lisbon"}, wantCode: 2},
+		{args: []string{"update", "--id", "1", "--tag", "trip-lisbon"}, wantStdout: "Record updated successfully (ID: 1)"},
+		{args: []string{"update", "--id", "5", "--untag", "taxi"}, wantStdout: "Record updated successfully (ID: 5)"},
+		{
+			args: []string{"list", "--tag", "business"},
+			wantStdout: "ID\tDate\t\tDescription\t\tAmount\tTags\n" +
+				"4\t2024-03-15\tHotel\t100\t#business #trip-lisbon\n" +
+				"5\t2024-03-15\tTaxi\t15\t#business\n",
+		},
+		{args: []string{"list", "--tag", "food"}, wantStdout: "ID\tDate\t\tDescription\t\tAmount\n"},
+		{args: []string{"summary", "--tag", "business", "--tag", "trip-lisbon"}, wantStdout: "Total expenses: 100"},
+		{args: []string{"summary", "--any-tag", "business", "--any-tag", "trip-lisbon"}, wantStdout: "Total expenses: 135"},
+		{args: []string{"summary", "--tag", "trip-lisbon", "--month", "1"}, wantStdout: "Total expenses: 20"},
+		{args: []string{"tags"}, wantStdout: "Tag           Count  Total\n#business     2      115\n#trip-lisbon  2      120\n"},
+		{args: []string{"tags", "--month", "1"}, wantStdout: "Tag           Count  Total\n#trip-lisbon  1      20\n"},
+		{
+			args:       []string{"export", "--output", "-", "--any-tag", "trip-lisbon"},
+			wantStdout: "Id,Date,Amount,Description,Kind,Account,Payee,Notes,Tags\n1,2024-01-15,20,Lunch,expense,default,,,#trip-lisbon\n4,2024-03-15,100,Hotel,expense,default,,,#business #trip-lisbon\n",
+		},
+	}
+
+	for _, step := range steps {
+		code, stdout, stderr := runCli(t, storage, "", step.args...)
+		if code != step.wantCode || (step.wantCode == 0 && stdout != step.wantStdout) {
+			t.Errorf("%v: exit code = %d, stdout = %q, stderr = %q, want %d, %q", step.args, code, stdout, stderr, step.wantCode, step.wantStdout)
+		}
+	}
+}
+
+func TestCliPayees(t *testing.T) {
+	storage := &FakeStorage{records: testRecords()}
+	steps := []struct {
+		args       []string
+		wantCode   int
+		wantStdout string
+	}{
+		{args: []string{"payees"}, wantStdout: "No payees"},
+		{args: []string{"add", "--description", "Groceries", "--amount", "40", "--payee", "Grocer", "--notes", "weekly shopping"}, wantStdout: "Expense added successfully (ID: 4)"},
+		{args: []string{"update", "--id", "1", "--payee", "Cafe Central"}, wantStdout: "Record updated successfully (ID: 1)"},
+		{args: []string{"update", "--id", "3", "--payee", "cafe central", "--notes", "with cake"}, wantStdout: "Record updated successfully (ID: 3)"},
+		{args: []string{"update", "--id", "4", "--notes", ""}, wantStdout: "Record updated successfully (ID: 4)"},
+		{
+			args: []string{"list", "--search", "CAFE"},
+			wantStdout: "ID\tDate\t\tDescription\t\tAmount\tPayee\n" +
+				"1\t2024-01-15\tLunch\t20\tCafe Central\n" +
+				"3\t2024-03-14\tCoffee\t5\tcafe central\n",
+		},
+		{args: []string{"list", "--search", "cake"}, wantStdout: "ID\tDate\t\tDescription\t\tAmount\tPayee\n3\t2024-03-14\tCoffee\t5\tcafe central\n"},
+		{args: []string{"list", "--search", "weekly"}, wantStdout: "ID\tDate\t\tDescription\t\tAmount\n"},
+		{args: []string{"payees"}, wantStdout: "Payee         Count  Total\nGrocer        1      40\nCafe Central  2      25\n"},
+		{args: []string{"payees", "--month", "1"}, wantStdout: "Payee         Count  Total\nCafe Central  1      20\n"},
+		{args: []string{"payees", "--month", "13"}, wantCode: 2},
+	}
+
+	for _, step := range steps {
+		code, stdout, stderr := runCli(t, storage, "", step.args...)
+		if code != step.wantCode || (step.wantCode == 0 && stdout != step.wantStdout) {
+			t.Errorf("%v: exit code = %d, stdout = %q, stderr = %q, want %d, %q", step.args, code, stdout, stderr, step.wantCode, step.wantStdout)
+		}
+	}
+}
+
+func TestCliIncome(t *testing.T) {
+	storage := &FakeStorage{records: testRecords()}
+	steps := []struct {
+		args       []string
+		wantCode   int
+		wantStdout string
+	}{
+		{args: []string{"summary"}, wantStdout: "Total expenses: 55"},
+		{args: []string{"add", "--description", "Salary", "--amount", "1000", "--income", "--tag", "work"}, wantStdout: "Income added successfully (ID: 4)"},
+		{args: []string{"add", "--description", "Rent", "--amount", "700", "--tag", "work"}, wantStdout: "Expense added successfully (ID: 5)"},
+		{args: []string{"summary"}, wantStdout: "Total expenses: 755\nTotal income: 1000\nNet: +245"},
+		{args: []string{"summary", "--month", "1"}, wantStdout: "Total expenses: 20"},
+		{args: []string{"summary", "--month", "3", "--tag", "work"}, wantStdout: "Total expenses: 700\nTotal income: 1000\nNet: +300"},
+		{args: []string{"list", "--tag", "work"}, wantStdout: "ID\tDate\t\tDescription\t\tAmount\tTags\n4\t2024-03-15\tSalary\t+1000\t#work\n5\t2024-03-15\tRent\t700\t#work\n"},
+		{args: []string{"tags"}, wantStdout: "Tag    Count  Total\n#work  1      700\n"},
+	}
+
+	for _, step := range steps {
+		code, stdout, stderr := runCli(t, storage, "", step.args...)
+		if code != step.wantCode || (step.wantCode == 0 && stdout != step.wantStdout) {
+			t.Errorf("%v: exit code = %d, stdout = %q, stderr = %q, want %d, %q", step.args, code, stdout, stderr, step.wantCode, step.wantStdout)
+		}
+	}
+}
+
+func TestCliAccounts(t *testing.T) {
+	storage := &FakeStorage{records: testRecords()}
+	steps := []struct {
+		args       []string
+		wantCode   int
+		wantStdout string
+	}{
+		{args: []string{"add", "--description", "Salary", "--amount", "1000", "--income", "--account", "checking"}, wantStdout: "Income added successfully (ID: 4)"},
+		{args: []string{"transfer", "--from", "checking", "--to", "cash", "--amount", "100"}, wantStdout: "Transfer added successfully (IDs: 5, 6)"},
+		{args: []string{"transfer", "--from", "cash", "--to", "cash", "--amount", "100"}, wantCode: 2},
+		{args: []string{"transfer", "--from", "cash", "--amount", "100"}, wantCode: 2},
+		{args: []string{"transfer", "--from", "cash", "--to", "checking"}, wantCode: 2},
+		{args: []string{"add", "--description", "Taxi", "--amount", "15", "--account", "cash"}, wantStdout: "Expense added successfully (ID: 7)"},
+		{args: []string{"summary"}, wantStdout: "Total expenses: 70\nTotal income: 1000\nNet: +930"},
+		{
+			args: []string{"list", "--account", "cash"},
+			wantStdout: "ID\tDate\t\tDescription\t\tAmount\tAccount\n" +
+				"6\t2024-03-15\tTransfer from checking to cash\t+100\tcash\n" +
+				"7\t2024-03-15\tTaxi\t15\tcash\n",
+		},
+		{
+			args: []string{"accounts"},
+			wantStdout: "Account   Income  Expenses  Transfers  Balance\n" +
+				"cash      0       15        +100       85\n" +
+				"checking  1000    0         -100       900\n" +
+				"default   0       55        +0         -55\n",
+		},
+		{args: []string{"delete", "--id", "6"}, wantStdout: "Record deleted successfully (ID: 6)"},
+		{
+			args: []string{"accounts"},
+			wantStdout: "Account   Income  Expenses  Transfers  Balance\n" +
+				"cash      0       15        +0         -15\n" +
+				"checking  1000    0         +0         1000\n" +
+				"default   0       55        +0         -55\n",
+		},
+	}
+
+	for _, step := range steps {
+		code, stdout, stderr := runCli(t, storage, "", step.args...)
+		if code != step.wantCode || (step.wantCode == 0 && stdout != step.wantStdout) {
+			t.Errorf("%v: exit code = %d, stdout = %q, stderr = %q, want %d, %q", step.args, code, stdout, stderr, step.wantCode, step.wantStdout)
+		}
+	}
+}
+
+func TestCliAttachments(t *testing.T) {
+	dir := t.TempDir()
+	receipt := filepath.Join(dir, "receipt.pdf")
+	if err := os.WriteFile(receipt, []byte("receipt"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	hash := sha256.Sum256([]byte("receipt"))
+	key := hex.EncodeToString(hash[:])
+
+	storage := &FakeStorage{records: testRecords()}
+	attachments := NewDirAttachmentStore(filepath.Join(dir, "attachments"))
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.Local)
+	backups := NewBackups(filepath.Join(dir, "backups"), defaultBackupRetention, FixedClock(now))
+	run := func(args ...string) (int, string) {
+		var out, errOut strings.Builder
+		env := &Env{
+			Stdin:       strings.NewReader(""),
+			Stdout:      &out,
+			Stderr:      &errOut,
+			Now:         FixedClock(now),
+			Location:    time.Local,
+			Attachments: attachments,
+			Backups:     backups,
+			OpenStorage: func() (TrackerStorage, error) {
+				return storage, nil
+			},
+		}
+		return ExitCode(Run(args, env)), out.String()
+	}
+
+	steps := []struct {
+		args       []string
+		wantCode   int
+		wantStdout string
+	}{
+		{args: []string{"attachments", "--id", "1"}, wantStdout: "No attachments for record 1"},
+		{args: []string{"attach", "--id", "1"}, wantCode: 2},
+		{args: []string{"attach", "--id", "9", receipt}, wantCode: 2},
+		{args: []string{"attach", "--id", "1", filepath.Join(dir, "missing.pdf")}, wantCode: 2},
+		{args: []string{"attach", "--id", "1", receipt}, wantStdout: "Attached receipt.pdf to record 1\n"},
+		{args: []string{"attachments", "--id", "1"}, wantStdout: "Name         Key\nreceipt.pdf  " + key + "\n"},
+		{args: []string{"attachments", "--id", "1", "--output", filepath.Join(dir, "out")}, wantStdout: "Name         Key\nreceipt.pdf  " + key + "\n"},
+		{args: []string{"delete", "--id", "1"}, wantStdout: "Record deleted successfully (ID: 1)"},
+		// the backup taken before delete still links to the receipt
+		{args: []string{"attachments", "--prune"}, wantStdout: "Removed 0 unused attachments"},
+	}
+
+	backup := filepath.Join(dir, "backups", backupName("expenses.csv", now.UTC()))
+	if err := os.MkdirAll(filepath.Dir(backup), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(backup, []byte("Id,CreatedAt,Amount,Description,Attachments\n1,2024-01-15T12:00:00Z,20,Lunch,"+key+" receipt.pdf\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, step := range steps {
+		code, stdout := run(step.args...)
+		if code != step.wantCode || (step.wantCode == 0 && stdout != step.wantStdout) {
+			t.Errorf("%v: exit code = %d, stdout = %q, want %d, %q", step.args, code, stdout, step.wantCode, step.wantStdout)
+		}
+	}
+
+	copied, err := os.ReadFile(filepath.Join(dir, "out", "receipt.pdf"))
+	if err != nil || string(copied) != "receipt" {
+		t.Errorf("copied attachment = %q, %v, want %q", copied, err, "receipt")
+	}
+	if keys, _ := attachments.Keys(); len(keys) != 1 {
+		t.Errorf("attachments after deleting the record = %v, want the receipt kept", keys)
+	}
+
+	if err := os.Remove(backup); err != nil {
+		t.Fatal(err)
+	}
+	if code, stdout := run("attachments", "--prune"); code != 0 || stdout != "Removed 1 unused attachments" {
+		t.Errorf("attachments --prune without backups = %d, %q, want the receipt removed", code, stdout)
+	}
+	if keys, _ := attachments.Keys(); len(keys) != 0 {
+		t.Errorf("attachments after prune = %v, want none", keys)
+	}
+}
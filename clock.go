@@ -0,0 +1,15 @@
+package main
+
+import (
+	"time"
+)
+
+// Clock returns the current time, the system clock is time.Now.
+type Clock func() time.Time
+
+// FixedClock always returns the same time, used to make date dependent behavior deterministic.
+func FixedClock(now time.Time) Clock {
+	return func() time.Time {
+		return now
+	}
+}
@@ -1,177 +1,1094 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
 	"time"
 )
 
-func HelpCmd() error {
-	fmt.Print(HelpText)
-	return nil
-}
+func HelpCmd(flags *flag.FlagSet, env *Env) func(tracker *Tracker) error {
+	return func(_ *Tracker) error {
+		if flags.NArg() == 0 {
+			fmt.Fprint(env.Stdout, HelpText())
+			return nil
+		}
 
-func AddCmd(args []string, tracker *Tracker) error {
-	addCmd := flag.NewFlagSet("add", flag.ExitOnError)
-	addCmd.Usage = func() {
-		fmt.Fprint(addCmd.Output(), "Usage of add:\nadd a new record to the tracker\n")
-		addCmd.PrintDefaults()
+		command, err := lookupCommand(flags.Arg(0))
+		if err != nil {
+			printUnknownCommand(env.Stderr, err)
+			return err
+		}
+		command.PrintUsage(env.Stdout, env)
+		return nil
 	}
+}
 
+func AddCmd(addCmd *flag.FlagSet, env *Env) func(tracker *Tracker) error {
 	description := addCmd.String("description", "", "text description, required")
 	amount := addCmd.Uint("amount", 0, "money amount, required, must be more than 0")
+	var tags tagsFlag
+	addCmd.Var(&tags, "tag", "`tag` like business or trip-lisbon, can be repeated")
+	payee := addCmd.String("payee", "", "merchant or person paid")
+	notes := addCmd.String("notes", "", "free-form notes")
+	income := addCmd.Bool("income", false, "record money received instead of an expense")
+	account := addCmd.String("account", "", "`account` paid from or received to, like cash or checking, default account if not set")
 
-	err := addCmd.Parse(args)
-	if err != nil {
-		return err
+	return func(tracker *Tracker) error {
+		if *amount == 0 {
+			addCmd.Usage()
+			return errors.New("invalid amount")
+		}
+
+		if *description == "" {
+			addCmd.Usage()
+			return errors.New("invalid description")
+		}
+
+		kind := KindExpense
+		if *income {
+			kind = KindIncome
+		}
+		record, err := tracker.AddRecord(TrackerRecord{Description: *description, Amount: *amount, Tags: tags, Payee: *payee, Notes: *notes, Kind: kind, Account: *account})
+		if err != nil {
+			fmt.Fprintf(env.Stderr, "error adding record: %v\n", err)
+			return err
+		}
+
+		if record.Kind == KindIncome {
+			fmt.Fprintf(env.Stdout, "Income added successfully (ID: %d)", record.Id)
+			return nil
+		}
+		fmt.Fprintf(env.Stdout, "Expense added successfully (ID: %d)", record.Id)
+
+		return nil
 	}
+}
 
-	if *amount == 0 {
-		addCmd.Usage()
-		return errors.New("invalid amount")
+func UpdateCmd(updateCmd *flag.FlagSet, env *Env) func(tracker *Tracker) error {
+	id := updateCmd.String("id", "", "record ID or UID, required")
+	description := updateCmd.String("description", "", "new text description")
+	amount := updateCmd.Uint("amount", DoNotUpdateAmount, "new money amount")
+	var addTags, removeTags tagsFlag
+	updateCmd.Var(&addTags, "tag", "`tag` to add, can be repeated")
+	updateCmd.Var(&removeTags, "untag", "`tag` to remove, can be repeated")
+	payee := updateCmd.String("payee", "", "new merchant or person paid, empty to clear")
+	notes := updateCmd.String("notes", "", "new free-form notes, empty to clear")
+	account := updateCmd.String("account", "", "new `account`, empty for the default account")
+
+	return func(tracker *Tracker) error {
+		if *id == "" {
+			updateCmd.Usage()
+			return invalidRecordId
+		}
+
+		change := RecordChange{Description: *description, Amount: *amount, AddTags: addTags, RemoveTags: removeTags}
+		if isFlagPassed(updateCmd, "payee") {
+			change.Payee = payee
+		}
+		if isFlagPassed(updateCmd, "notes") {
+			change.Notes = notes
+		}
+		if isFlagPassed(updateCmd, "account") {
+			change.Account = account
+		}
+		if *description == "" && *amount == DoNotUpdateAmount && len(addTags) == 0 && len(removeTags) == 0 && change.Payee == nil && change.Notes == nil && change.Account == nil {
+			updateCmd.Usage()
+			return errors.New("required description, amount, tags, payee, notes or account")
+		}
+
+		recordId, err := tracker.ResolveId(*id)
+		if errors.Is(err, invalidRecordId) {
+			updateCmd.Usage()
+			return err
+		}
+		if err != nil {
+			fmt.Fprintf(env.Stderr, "error updating record: %v\n", err)
+			return err
+		}
+
+		record, err := tracker.UpdateRecord(recordId, change)
+		if err != nil {
+			fmt.Fprintf(env.Stderr, "error updating record: %v\n", err)
+			return err
+		}
+
+		fmt.Fprintf(env.Stdout, "Record updated successfully (ID: %d)", record.Id)
+
+		return nil
 	}
+}
+
+func DeleteCmd(deleteCmd *flag.FlagSet, env *Env) func(tracker *Tracker) error {
+	id := deleteCmd.String("id", "", "record ID or UID, required")
 
-	if *description == "" {
-		addCmd.Usage()
-		return errors.New("invalid description")
+	return func(tracker *Tracker) error {
+		recordId, err := tracker.ResolveId(*id)
+		if errors.Is(err, invalidRecordId) {
+			deleteCmd.Usage()
+			return err
+		}
+		if err == nil {
+			err = tracker.Delete(recordId)
+		}
+		if err != nil {
+			fmt.Fprintf(env.Stderr, "error deleting record: %v\n", err)
+			return err
+		}
+
+		fmt.Fprintf(env.Stdout, "Record deleted successfully (ID: %d)", recordId)
+
+		return nil
 	}
+}
+
+func AttachCmd(attachCmd *flag.FlagSet, env *Env) func(tracker *Tracker) error {
+	id := attachCmd.String("id", "", "record ID or UID, required")
 
-	record, err := tracker.Add(*description, *amount)
+	return func(tracker *Tracker) error {
+		if attachCmd.NArg() == 0 {
+			attachCmd.Usage()
+			return errors.New("required file")
+		}
+		recordId, err := tracker.ResolveId(*id)
+		if errors.Is(err, invalidRecordId) {
+			attachCmd.Usage()
+			return err
+		}
+		if err != nil {
+			fmt.Fprintf(env.Stderr, "error attaching file: %v\n", err)
+			return err
+		}
+
+		for _, path := range attachCmd.Args() {
+			err := attachFile(tracker, recordId, path)
+			if err != nil {
+				fmt.Fprintf(env.Stderr, "error attaching file: %v\n", err)
+				return err
+			}
+			fmt.Fprintf(env.Stdout, "Attached %s to record %d\n", filepath.Base(path), recordId)
+		}
+		return nil
+	}
+}
+
+func attachFile(tracker *Tracker, id RecordId, path string) error {
+	file, err := os.Open(path)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error adding record: %v\n", err)
 		return err
 	}
+	defer file.Close()
+	_, err = tracker.Attach(id, filepath.Base(path), file)
+	return err
+}
 
-	fmt.Printf("Expense added successfully (ID: %d)", record.Id)
+func AttachmentsCmd(attachmentsCmd *flag.FlagSet, env *Env) func(tracker *Tracker) error {
+	id := attachmentsCmd.String("id", "", "record ID or UID, required unless --prune is set")
+	output := attachmentsCmd.String("output", "", "copy attached files to the `directory`")
+	prune := attachmentsCmd.Bool("prune", false, "remove stored files no record of the ledger or of its backups links to")
 
-	return nil
+	return func(tracker *Tracker) error {
+		if *prune {
+			return pruneAttachments(tracker, env)
+		}
+
+		recordId, err := tracker.ResolveId(*id)
+		if errors.Is(err, invalidRecordId) {
+			attachmentsCmd.Usage()
+			return err
+		}
+		var record TrackerRecord
+		if err == nil {
+			record, err = tracker.Get(recordId)
+		}
+		if err != nil {
+			fmt.Fprintf(env.Stderr, "error listing attachments: %v\n", err)
+			return err
+		}
+
+		if *output != "" {
+			for _, attachment := range record.Attachments {
+				err := copyAttachment(tracker, attachment, *output)
+				if err != nil {
+					fmt.Fprintf(env.Stderr, "error copying attachment: %v\n", err)
+					return err
+				}
+			}
+		}
+
+		if len(record.Attachments) == 0 {
+			fmt.Fprintf(env.Stdout, "No attachments for record %d", recordId)
+			return nil
+		}
+		table := tabwriter.NewWriter(env.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(table, "Name\tKey")
+		for _, attachment := range record.Attachments {
+			fmt.Fprintf(table, "%s\t%s\n", attachment.Name, attachment.Key)
+		}
+		return table.Flush()
+	}
 }
 
-func UpdateCmd(args []string, tracker *Tracker) error {
-	updateCmd := flag.NewFlagSet("update", flag.ExitOnError)
-	updateCmd.Usage = func() {
-		fmt.Fprint(updateCmd.Output(), "Usage of update:\nset new description and/or amount to record with specified id, at least one optional parameter must be specified\n")
-		updateCmd.PrintDefaults()
+func pruneAttachments(tracker *Tracker, env *Env) error {
+	backups, err := readBackups(env)
+	if err != nil {
+		fmt.Fprintf(env.Stderr, "error reading backups: %v\n", err)
+		return err
+	}
+	// keys are long enough for a plain search in backup content to be exact
+	removed, err := tracker.PruneAttachments(func(key string) bool {
+		return slices.ContainsFunc(backups, func(backup []byte) bool {
+			return bytes.Contains(backup, []byte(key))
+		})
+	})
+	if err != nil {
+		fmt.Fprintf(env.Stderr, "error pruning attachments: %v\n", err)
+		return err
 	}
 
-	id := updateCmd.Uint("id", InvalidId, "record ID, required")
-	description := updateCmd.String("description", "", "new text description")
-	amount := updateCmd.Uint("amount", DoNotUpdateAmount, "new money amount")
+	fmt.Fprintf(env.Stdout, "Removed %d unused attachments", len(removed))
+	return nil
+}
 
-	err := updateCmd.Parse(args)
+// readBackups returns plaintext content of all backups, the passphrase is asked once if some backups are encrypted.
+func readBackups(env *Env) ([][]byte, error) {
+	if env.Backups == nil {
+		return nil, nil
+	}
+	list, err := env.Backups.List()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if *id == InvalidId {
-		updateCmd.Usage()
-		return errors.New("invalid ID")
+	contents := make([][]byte, 0, len(list))
+	passphrase := ""
+	for _, backup := range list {
+		data, err := env.Backups.Read(backup.Name)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.HasPrefix(data, []byte(encryptedMagic)) {
+			if passphrase == "" {
+				passphrase, err = env.Passphrase("Passphrase of backups: ")
+				if err != nil {
+					return nil, err
+				}
+			}
+			data, _, _, err = decryptLedger(data, passphrase)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", backup.Name, err)
+			}
+		}
+		contents = append(contents, data)
 	}
+	return contents, nil
+}
 
-	if *description == "" && *amount == DoNotUpdateAmount {
-		updateCmd.Usage()
-		return errors.New("required description or amount")
+func copyAttachment(tracker *Tracker, attachment Attachment, dir string) error {
+	content, err := tracker.OpenAttachment(attachment.Key)
+	if err != nil {
+		return fmt.Errorf("%s: %w", attachment.Name, err)
 	}
+	defer content.Close()
 
-	record, err := tracker.Update(RecordId(*id), *description, *amount)
+	err = os.MkdirAll(dir, 0700)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error updating record: %v\n", err)
 		return err
 	}
+	file, err := os.Create(filepath.Join(dir, attachment.Name))
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, content)
+	closeErr := file.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
 
-	fmt.Printf("Record updated successfully (ID: %d)", record.Id)
+func ListCmd(listCmd *flag.FlagSet, env *Env) func(tracker *Tracker) error {
+	tagFilter := addTagFilterFlags(listCmd, "list records")
+	search := listCmd.String("search", "", "list records containing the text in description, payee, notes or tags")
+	account := listCmd.String("account", "", "list records of the `account`")
 
-	return nil
+	return func(tracker *Tracker) error {
+		records := tagFilter.Filter(tracker.GetAll())
+		if *search != "" {
+			records = SearchRecords(records, *search)
+		}
+		if *account != "" {
+			name, err := normalizeAccount(*account)
+			if err != nil {
+				listCmd.Usage()
+				return err
+			}
+			records = slices.DeleteFunc(records, func(record TrackerRecord) bool {
+				return record.Account != name
+			})
+		}
+		// account, payee and tags columns are shown only when there is something to show
+		hasAccounts := slices.ContainsFunc(records, func(record TrackerRecord) bool {
+			return record.Account != ""
+		})
+		hasPayees := slices.ContainsFunc(records, func(record TrackerRecord) bool {
+			return record.Payee != ""
+		})
+		hasTags := slices.ContainsFunc(records, func(record TrackerRecord) bool {
+			return len(record.Tags) > 0
+		})
+
+		fmt.Fprint(env.Stdout, "ID\tDate\t\tDescription\t\tAmount")
+		if hasAccounts {
+			fmt.Fprint(env.Stdout, "\tAccount")
+		}
+		if hasPayees {
+			fmt.Fprint(env.Stdout, "\tPayee")
+		}
+		if hasTags {
+			fmt.Fprint(env.Stdout, "\tTags")
+		}
+		fmt.Fprintln(env.Stdout)
+		for _, record := range records {
+			fmt.Fprintf(env.Stdout, "%d\t%s\t%s\t%s", record.Id, record.CreatedAt.In(env.Location).Format(time.DateOnly), record.Description, formatSignedAmount(record))
+			if hasAccounts {
+				fmt.Fprintf(env.Stdout, "\t%s", accountName(record))
+			}
+			if hasPayees {
+				fmt.Fprintf(env.Stdout, "\t%s", record.Payee)
+			}
+			if hasTags {
+				fmt.Fprintf(env.Stdout, "\t%s", formatTags(record.Tags))
+			}
+			fmt.Fprintln(env.Stdout)
+		}
+		return nil
+	}
 }
 
-func DeleteCmd(args []string, tracker *Tracker) error {
-	deleteCmd := flag.NewFlagSet("delete", flag.ExitOnError)
-	deleteCmd.Usage = func() {
-		fmt.Fprint(deleteCmd.Output(), "Usage of delete:\ndelete record with specified id\n")
-		deleteCmd.PrintDefaults()
+func SummaryCmd(summaryCmd *flag.FlagSet, env *Env) func(tracker *Tracker) error {
+	periodFlags := addPeriodFlags(summaryCmd, "show total expenses", env.Now().In(env.Location))
+	tagFilter := addTagFilterFlags(summaryCmd, "show total expenses")
+
+	return func(tracker *Tracker) error {
+		period, ok, err := periodFlags.Period(env.Location)
+		if err != nil {
+			summaryCmd.Usage()
+			return err
+		}
+
+		records := tracker.GetAll()
+		if ok {
+			records = tracker.GetByPeriod(period)
+		}
+		balance := ComputeBalance(tagFilter.Filter(records))
+
+		fmt.Fprintf(env.Stdout, "Total expenses: %d", balance.Expenses)
+		// income and net are shown only when there was income, ledgers of expenses keep the short summary
+		if balance.Income > 0 {
+			fmt.Fprintf(env.Stdout, "\nTotal income: %d\nNet: %+d", balance.Income, balance.Net())
+		}
+		return nil
 	}
+}
 
-	id := deleteCmd.Uint("id", InvalidId, "record ID, required")
+func TagsCmd(tagsCmd *flag.FlagSet, env *Env) func(tracker *Tracker) error {
+	periodFlags := addPeriodFlags(tagsCmd, "count tags", env.Now().In(env.Location))
 
-	err := deleteCmd.Parse(args)
-	if err != nil {
-		return err
+	return func(tracker *Tracker) error {
+		period, ok, err := periodFlags.Period(env.Location)
+		if err != nil {
+			tagsCmd.Usage()
+			return err
+		}
+
+		records := tracker.GetAll()
+		if ok {
+			records = tracker.GetByPeriod(period)
+		}
+		totals := ComputeTagTotals(expensesOnly(records))
+		if len(totals) == 0 {
+			fmt.Fprint(env.Stdout, "No tags")
+			return nil
+		}
+
+		table := tabwriter.NewWriter(env.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(table, "Tag\tCount\tTotal")
+		for _, total := range totals {
+			fmt.Fprintf(table, "%s\t%d\t%d\n", formatTags([]string{total.Tag}), total.Count, total.Total)
+		}
+		return table.Flush()
 	}
+}
 
-	if *id == InvalidId {
-		deleteCmd.Usage()
-		return errors.New("invalid ID")
+func PayeesCmd(payeesCmd *flag.FlagSet, env *Env) func(tracker *Tracker) error {
+	periodFlags := addPeriodFlags(payeesCmd, "show spending per payee", env.Now().In(env.Location))
+
+	return func(tracker *Tracker) error {
+		period, ok, err := periodFlags.Period(env.Location)
+		if err != nil {
+			payeesCmd.Usage()
+			return err
+		}
+
+		records := tracker.GetAll()
+		if ok {
+			records = tracker.GetByPeriod(period)
+		}
+		totals := ComputePayeeTotals(expensesOnly(records))
+		if len(totals) == 0 {
+			fmt.Fprint(env.Stdout, "No payees")
+			return nil
+		}
+
+		table := tabwriter.NewWriter(env.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(table, "Payee\tCount\tTotal")
+		for _, total := range totals {
+			fmt.Fprintf(table, "%s\t%d\t%d\n", total.Payee, total.Count, total.Total)
+		}
+		return table.Flush()
 	}
+}
 
-	err = tracker.Delete(RecordId(*id))
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "error deleting record: %v\n", err)
-		return err
+func TransferCmd(transferCmd *flag.FlagSet, env *Env) func(tracker *Tracker) error {
+	from := transferCmd.String("from", "", "`account` the money is taken from, required")
+	to := transferCmd.String("to", "", "`account` the money is moved to, required")
+	amount := transferCmd.Uint("amount", 0, "money amount, required, must be more than 0")
+	description := transferCmd.String("description", "", "text description, \"Transfer from <from> to <to>\" if not set")
+
+	return func(tracker *Tracker) error {
+		if *from == "" || *to == "" {
+			transferCmd.Usage()
+			return errors.New("required from and to accounts")
+		}
+		if *amount == 0 {
+			transferCmd.Usage()
+			return errors.New("invalid amount")
+		}
+
+		if *description == "" {
+			*description = fmt.Sprintf("Transfer from %s to %s", *from, *to)
+		}
+		out, in, err := tracker.Transfer(*from, *to, *amount, *description)
+		if err != nil {
+			fmt.Fprintf(env.Stderr, "error adding transfer: %v\n", err)
+			return err
+		}
+
+		fmt.Fprintf(env.Stdout, "Transfer added successfully (IDs: %d, %d)", out.Id, in.Id)
+		return nil
 	}
+}
 
-	fmt.Printf("Record deleted successfully (ID: %d)", *id)
+func AccountsCmd(accountsCmd *flag.FlagSet, env *Env) func(tracker *Tracker) error {
+	return func(tracker *Tracker) error {
+		balances := ComputeAccountBalances(tracker.GetAll())
+		if len(balances) == 0 {
+			fmt.Fprint(env.Stdout, "No accounts")
+			return nil
+		}
 
-	return nil
+		table := tabwriter.NewWriter(env.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(table, "Account\tIncome\tExpenses\tTransfers\tBalance")
+		for _, balance := range balances {
+			transfers := int64(balance.TransfersIn) - int64(balance.TransfersOut)
+			fmt.Fprintf(table, "%s\t%d\t%d\t%+d\t%d\n", balance.Account, balance.Income, balance.Expenses, transfers, balance.Balance())
+		}
+		return table.Flush()
+	}
 }
 
-func ListCmd(_ []string, tracker *Tracker) error {
-	fmt.Println("ID\tDate\t\tDescription\t\tAmount")
-	for _, record := range tracker.GetAll() {
-		fmt.Printf("%d\t%s\t%s\t%d\n", record.Id, record.CreatedAt.Format(time.DateOnly), record.Description, record.Amount)
+func isFlagPassed(flags *flag.FlagSet, name string) bool {
+	found := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			found = true
+		}
+	})
+	return found
+}
+
+// exportFormatOf detects the export format from the output file extension in any case, csv if it is not a known format.
+func exportFormatOf(output string) string {
+	format := strings.ToLower(strings.TrimPrefix(filepath.Ext(output), "."))
+	if !isExportFormat(format) {
+		return ExportCsv
 	}
-	return nil
+	return format
 }
 
-func SummaryCmd(args []string, tracker *Tracker) error {
-	summaryCmd := flag.NewFlagSet("summary", flag.ExitOnError)
-	summaryCmd.Usage = func() {
-		fmt.Fprint(summaryCmd.Output(), "Usage of summary:\nshow total expenses for all time, can set optional parameters to show total expenses for specified period\n")
-		summaryCmd.PrintDefaults()
+func isExportFormat(format string) bool {
+	return format == ExportCsv || format == ExportJson || format == ExportXlsx
+}
+
+func ExportCmd(exportCmd *flag.FlagSet, env *Env) func(tracker *Tracker) error {
+	format := exportCmd.String("format", "", "output format: csv, json or xlsx, detected from output file extension by default, csv if undetected")
+	output := exportCmd.String("output", "", "output file path, required, use - to write to stdout")
+	periodFlags := addPeriodFlags(exportCmd, "export records", env.Now().In(env.Location))
+	delimiter := exportCmd.String("delimiter", ",", "csv field delimiter")
+	dateFormat := exportCmd.String("date-format", time.DateOnly, "csv date format as Go time layout")
+	tagFilter := addTagFilterFlags(exportCmd, "export records")
+
+	return func(tracker *Tracker) error {
+		if *output == "" {
+			exportCmd.Usage()
+			return errors.New("invalid output")
+		}
+
+		if *format == "" {
+			*format = exportFormatOf(*output)
+		}
+		if !isExportFormat(*format) {
+			exportCmd.Usage()
+			return errors.New("invalid format")
+		}
+
+		delimiterRunes := []rune(*delimiter)
+		if len(delimiterRunes) != 1 {
+			exportCmd.Usage()
+			return errors.New("invalid delimiter")
+		}
+
+		period, _, err := periodFlags.Period(env.Location)
+		if err != nil {
+			exportCmd.Usage()
+			return err
+		}
+		records := inLocation(tagFilter.Filter(tracker.GetByPeriod(period)), env.Location)
+
+		var file io.Writer = env.Stdout
+		var created *os.File
+		if *output != "-" {
+			created, err = os.Create(*output)
+			if err != nil {
+				fmt.Fprintf(env.Stderr, "error creating file: %v\n", err)
+				return err
+			}
+			file = created
+		}
+
+		switch *format {
+		case ExportCsv:
+			err = ExportToCsv(file, records, delimiterRunes[0], *dateFormat)
+		case ExportJson:
+			err = ExportToJson(file, records)
+		case ExportXlsx:
+			err = ExportToXlsx(file, records)
+		}
+		// data may be written on close, a failed close means an incomplete file
+		if created != nil {
+			err = errors.Join(err, created.Close())
+		}
+		if err != nil {
+			fmt.Fprintf(env.Stderr, "error exporting records: %v\n", err)
+			return err
+		}
+
+		if *output != "-" {
+			fmt.Fprintf(env.Stdout, "Exported %d records to %s", len(records), *output)
+		}
+
+		return nil
 	}
+}
+
+func ReportCmd(reportCmd *flag.FlagSet, env *Env) func(tracker *Tracker) error {
+	by := reportCmd.String("by", string(ByMonth), "split period by day, week or month")
+	now := env.Now().In(env.Location)
+	periodFlags := addPeriodFlags(reportCmd, "show report", now)
+
+	return func(tracker *Tracker) error {
+		granularity, err := ParseGranularity(*by)
+		if err != nil {
+			reportCmd.Usage()
+			return err
+		}
+
+		period, err := reportPeriod(periodFlags, granularity, now, env.Location)
+		if err != nil {
+			reportCmd.Usage()
+			return err
+		}
 
-	month := summaryCmd.Int("month", int(time.Now().Month()), "show total expenses for the specified month (1-12)")
-	year := summaryCmd.Int("year", time.Now().Year(), "show total expenses for the specified month (1-12)")
+		totals := tracker.GetBreakdown(period, granularity)
 
-	err := summaryCmd.Parse(args)
+		writer := tabwriter.NewWriter(env.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(writer, "Period\tTotal\tCount\tAverage\t")
+		var summary PeriodTotal
+		for _, total := range totals {
+			fmt.Fprintf(writer, "%s\t%d\t%d\t%.2f\t\n", total.Period.Label(granularity), total.Total, total.Count, total.Average())
+			summary.Total += total.Total
+			summary.Count += total.Count
+		}
+		fmt.Fprintf(writer, "Total\t%d\t%d\t%.2f\t\n", summary.Total, summary.Count, summary.Average())
+		return writer.Flush()
+	}
+}
+
+// reportPeriod returns a bounded period in location selected by flags, open end is today,
+// by default it is the current year for months, the current month for weeks or the current week for days.
+func reportPeriod(periodFlags *periodFlags, by Granularity, now time.Time, loc *time.Location) (Period, error) {
+	now = now.In(loc)
+	period, ok, err := periodFlags.Period(loc)
 	if err != nil {
-		return err
+		return Period{}, err
+	}
+	if !ok {
+		switch by {
+		case ByDay:
+			year, week := now.ISOWeek()
+			period, _ = IsoWeekPeriod(week, year, loc)
+		case ByWeek:
+			period = MonthPeriod(now.Month(), now.Year(), loc)
+		default:
+			period = YearPeriod(now.Year(), loc)
+		}
+	}
+	if period.From.IsZero() {
+		return Period{}, errors.New("period must have a start date")
+	}
+	if period.To.IsZero() {
+		period.To = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc).AddDate(0, 0, 1)
 	}
+	return period, nil
+}
+
+func ChartCmd(chartCmd *flag.FlagSet, env *Env) func(tracker *Tracker) error {
+	by := chartCmd.String("by", string(ByMonth), "split period by day, week or month")
+	width := chartCmd.Int("width", 0, "chart width in characters, defaults to terminal width")
+	now := env.Now().In(env.Location)
+	periodFlags := addPeriodFlags(chartCmd, "draw chart", now)
+
+	return func(tracker *Tracker) error {
+		granularity, err := ParseGranularity(*by)
+		if err != nil {
+			chartCmd.Usage()
+			return err
+		}
+
+		// detected on run, listing flags for help and completions must not query the terminal
+		if *width == 0 {
+			*width = terminalWidth(env.Stdout)
+		}
+		if *width < 10 {
+			chartCmd.Usage()
+			return errors.New("invalid width")
+		}
+
+		period, err := reportPeriod(periodFlags, granularity, now, env.Location)
+		if err != nil {
+			chartCmd.Usage()
+			return err
+		}
+
+		totals := tracker.GetBreakdown(period, granularity)
+		values := make([]uint, len(totals))
+		bars := make([]ChartBar, len(totals))
+		for i, total := range totals {
+			values[i] = total.Total
+			bars[i] = ChartBar{Label: total.Period.Label(granularity), Value: total.Total}
+		}
+
+		fmt.Fprintln(env.Stdout, Sparkline(values))
+		fmt.Fprintln(env.Stdout)
+		return RenderBarChart(env.Stdout, bars, *width)
+	}
+}
+
+// terminalWidth reads the width from COLUMNS environment variable when it is set,
+// from the terminal when output is a terminal, 80 otherwise.
+func terminalWidth(output io.Writer) int {
+	width, err := strconv.Atoi(os.Getenv("COLUMNS"))
+	if err == nil && width > 0 {
+		return width
+	}
+	if file, ok := terminalFile(output); ok {
+		width, _ = terminalSize(file)
+		return width
+	}
+	return 80
+}
+
+func StatsCmd(statsCmd *flag.FlagSet, env *Env) func(tracker *Tracker) error {
+	top := statsCmd.Int("top", 5, "number of largest expenses to show")
+	periodFlags := addPeriodFlags(statsCmd, "show statistics", env.Now().In(env.Location))
+
+	return func(tracker *Tracker) error {
+		if *top < 0 {
+			statsCmd.Usage()
+			return errors.New("invalid top")
+		}
+
+		period, _, err := periodFlags.Period(env.Location)
+		if err != nil {
+			statsCmd.Usage()
+			return err
+		}
+
+		stats := ComputeStats(inLocation(expensesOnly(tracker.GetByPeriod(period)), env.Location), *top)
+
+		writer := tabwriter.NewWriter(env.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(writer, "Count:\t%d\n", stats.Count)
+		fmt.Fprintf(writer, "Total:\t%d\n", stats.Total)
+		fmt.Fprintf(writer, "Mean:\t%.2f\n", stats.Mean)
+		fmt.Fprintf(writer, "Median:\t%.2f\n", stats.Median)
+		fmt.Fprintf(writer, "Min:\t%d\n", stats.Min)
+		fmt.Fprintf(writer, "Max:\t%d\n", stats.Max)
+		fmt.Fprintf(writer, "Std deviation:\t%.2f\n", stats.StdDev)
+		for i, percent := range StatsPercentiles {
+			fmt.Fprintf(writer, "Percentile %g:\t%.2f\n", percent, stats.Percentiles[i])
+		}
+
+		if len(stats.Largest) > 0 {
+			fmt.Fprintln(writer, "\nLargest expenses:")
+			fmt.Fprintln(writer, "ID\tDate\tDescription\tAmount")
+			for _, record := range stats.Largest {
+				fmt.Fprintf(writer, "%d\t%s\t%s\t%d\n", record.Id, record.CreatedAt.Format(time.DateOnly), record.Description, record.Amount)
+			}
+		}
+
+		if stats.Count > 0 {
+			fmt.Fprintln(writer, "\nBusiest weekdays:")
+			fmt.Fprintln(writer, "Weekday\tCount\tTotal")
+			for _, weekday := range stats.Weekdays {
+				if weekday.Count == 0 {
+					continue
+				}
+				fmt.Fprintf(writer, "%s\t%d\t%d\n", weekday.Weekday, weekday.Count, weekday.Total)
+			}
+		}
+
+		return writer.Flush()
+	}
+}
+
+func ServeCmd(serveCmd *flag.FlagSet, env *Env) func(tracker *Tracker) error {
+	addr := serveCmd.String("addr", "localhost:8080", "address to listen on, use :8080 to listen on all interfaces")
+
+	return func(tracker *Tracker) error {
+		server := &http.Server{
+			Addr:              *addr,
+			Handler:           NewServer(tracker),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
 
-	if len(args) == 0 {
-		fmt.Printf("Total expenses: %d", tracker.GetSummary())
+		errs := make(chan error, 1)
+		go func() {
+			errs <- server.ListenAndServe()
+		}()
+		fmt.Fprintf(env.Stdout, "Listening on %s\n", *addr)
+
+		select {
+		case err := <-errs:
+			fmt.Fprintf(env.Stderr, "error serving: %v\n", err)
+			return err
+		case <-ctx.Done():
+		}
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return server.Shutdown(shutdownCtx)
+	}
+}
+
+func EncryptCmd(encryptCmd *flag.FlagSet, env *Env) func(tracker *Tracker) error {
+	input := encryptCmd.String("input", defaultStorageFile, "plaintext ledger file")
+	output := encryptCmd.String("output", "", "encrypted ledger file, input file with .enc extension by default")
+	keep := encryptCmd.Bool("keep", false, "keep the plaintext file")
+
+	return func(_ *Tracker) error {
+		if *output == "" {
+			*output = *input + ".enc"
+		}
+
+		_, err := os.Stat(*input)
+		if err != nil {
+			fmt.Fprintf(env.Stderr, "error reading ledger: %v\n", err)
+			return err
+		}
+		if isEncryptedFile(*input) {
+			encryptCmd.Usage()
+			return errors.New("ledger is already encrypted")
+		}
+		_, err = os.Stat(*output)
+		if err == nil {
+			encryptCmd.Usage()
+			return fmt.Errorf("%s already exists", *output)
+		}
+
+		plaintextStorage := NewStorageFromFile(*input)
+		records, err := plaintextStorage.ReadAll()
+		if err != nil {
+			fmt.Fprintf(env.Stderr, "error reading ledger: %v\n", err)
+			return err
+		}
+
+		passphrase, err := env.Passphrase("New passphrase: ")
+		if err != nil {
+			fmt.Fprintf(env.Stderr, "error reading passphrase: %v\n", err)
+			return err
+		}
+		if passphrase == "" {
+			return errors.New("empty passphrase")
+		}
+		confirmation, err := env.Passphrase("Repeat passphrase: ")
+		if err != nil {
+			fmt.Fprintf(env.Stderr, "error reading passphrase: %v\n", err)
+			return err
+		}
+		if confirmation != passphrase {
+			fmt.Fprintln(env.Stderr, "passphrases do not match")
+			return errors.New("passphrases do not match")
+		}
+
+		encryptedStorage := NewEncryptedStorage(*output, passphrase)
+		encryptedStorage.SetLastId(plaintextStorage.LastId())
+		err = encryptedStorage.Save(records)
+		if err != nil {
+			fmt.Fprintf(env.Stderr, "error writing encrypted ledger: %v\n", err)
+			return err
+		}
+		// read back before removing the only plaintext copy
+		saved, err := NewEncryptedStorage(*output, passphrase).ReadAll()
+		if err != nil || len(saved) != len(records) {
+			fmt.Fprintf(env.Stderr, "error verifying encrypted ledger: %v\n", err)
+			return errors.Join(errors.New("encrypted ledger verification failed"), err)
+		}
+
+		// plaintext backups would leave the records readable next to the encrypted ledger
+		backups := 0
+		if !*keep && env.Backups != nil {
+			backups, err = env.Backups.Convert(filepath.Base(*input), filepath.Base(*output), encryptedStorage.Seal)
+			if err != nil {
+				fmt.Fprintf(env.Stderr, "error encrypting backups: %v\n", err)
+				return err
+			}
+		}
+
+		if !*keep {
+			err = os.Remove(*input)
+			if err != nil {
+				fmt.Fprintf(env.Stderr, "error removing plaintext ledger: %v\n", err)
+				return err
+			}
+		}
+
+		fmt.Fprintf(env.Stdout, "Encrypted %d records to %s", len(records), *output)
+		if backups > 0 {
+			fmt.Fprintf(env.Stdout, "\nEncrypted %d backups", backups)
+		}
 		return nil
 	}
+}
+
+func DecryptCmd(decryptCmd *flag.FlagSet, env *Env) func(tracker *Tracker) error {
+	input := decryptCmd.String("input", defaultEncryptedFile, "encrypted ledger file")
+	output := decryptCmd.String("output", defaultStorageFile, "plaintext ledger file")
+	keep := decryptCmd.Bool("keep", false, "keep the encrypted file")
+
+	return func(_ *Tracker) error {
+		_, err := os.Stat(*input)
+		if err != nil {
+			fmt.Fprintf(env.Stderr, "error reading ledger: %v\n", err)
+			return err
+		}
+		_, err = os.Stat(*output)
+		if err == nil {
+			decryptCmd.Usage()
+			return fmt.Errorf("%s already exists", *output)
+		}
+
+		passphrase, err := env.Passphrase("Passphrase: ")
+		if err != nil {
+			fmt.Fprintf(env.Stderr, "error reading passphrase: %v\n", err)
+			return err
+		}
+		encryptedStorage := NewEncryptedStorage(*input, passphrase)
+		records, err := encryptedStorage.ReadAll()
+		if err != nil {
+			fmt.Fprintf(env.Stderr, "error reading encrypted ledger: %v\n", err)
+			return err
+		}
+
+		plaintextStorage := NewStorageFromFile(*output)
+		plaintextStorage.SetLastId(encryptedStorage.LastId())
+		err = plaintextStorage.Save(records)
+		if err != nil {
+			fmt.Fprintf(env.Stderr, "error writing ledger: %v\n", err)
+			return err
+		}
 
-	isMonthPassed := isFlagPassed(summaryCmd, "month")
-	isYearPassed := isFlagPassed(summaryCmd, "year")
+		if !*keep {
+			err = os.Remove(*input)
+			if err != nil {
+				fmt.Fprintf(env.Stderr, "error removing encrypted ledger: %v\n", err)
+				return err
+			}
+		}
 
-	if isMonthPassed && (*month < 1 || *month > 12) {
-		summaryCmd.Usage()
-		return errors.New("invalid month")
+		fmt.Fprintf(env.Stdout, "Decrypted %d records to %s", len(records), *output)
+		return nil
 	}
-	if isYearPassed && (*year < 1970 || *year > 9999) {
-		summaryCmd.Usage()
-		return errors.New("invalid year")
+}
+
+func BackupCmd(backupCmd *flag.FlagSet, env *Env) func(tracker *Tracker) error {
+	return func(_ *Tracker) error {
+		if env.Backups == nil {
+			fmt.Fprintf(env.Stderr, "backups are disabled by %s\n", backupsVariable)
+			return errors.New("backups are disabled")
+		}
+
+		switch {
+		case backupCmd.NArg() == 1 && backupCmd.Arg(0) == "list":
+			backups, err := env.Backups.List()
+			if err != nil {
+				fmt.Fprintf(env.Stderr, "error listing backups: %v\n", err)
+				return err
+			}
+			if len(backups) == 0 {
+				fmt.Fprint(env.Stdout, "No backups")
+				return nil
+			}
+			writer := tabwriter.NewWriter(env.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(writer, "Name\tLedger\tDate\tSize")
+			for _, backup := range backups {
+				fmt.Fprintf(writer, "%s\t%s\t%s\t%d\n", backup.Name, backup.Ledger, backup.Time.In(env.Location).Format(time.DateTime), backup.Size)
+			}
+			return writer.Flush()
+
+		case backupCmd.NArg() == 2 && backupCmd.Arg(0) == "restore":
+			err := checkRestoreFormat(backupCmd.Arg(1))
+			if err != nil {
+				fmt.Fprintf(env.Stderr, "error restoring backup: %v\n", err)
+				return err
+			}
+			ledger, err := env.Backups.Restore(backupCmd.Arg(1), filepath.Dir(defaultStorageFile))
+			if err != nil {
+				fmt.Fprintf(env.Stderr, "error restoring backup: %v\n", err)
+				return err
+			}
+			fmt.Fprintf(env.Stdout, "Restored %s from %s", ledger, backupCmd.Arg(1))
+			return nil
+
+		default:
+			backupCmd.Usage()
+			return errors.New("invalid backup command")
+		}
 	}
+}
 
-	var sum uint
-	if isYearPassed && !isMonthPassed {
-		sum = tracker.GetSummaryByYear(*year)
-	} else {
-		sum = tracker.GetSummaryByMonth(time.Month(*month), *year)
+// checkRestoreFormat refuses restoring a backup of the plaintext ledger while the ledger is encrypted and the other way round,
+// both ledgers next to each other cannot be opened.
+func checkRestoreFormat(name string) error {
+	backup, ok := parseBackupName(name)
+	if !ok {
+		return backupNotFound
+	}
+	other := ""
+	switch backup.Ledger {
+	case filepath.Base(defaultStorageFile):
+		other = defaultEncryptedFile
+	case filepath.Base(defaultEncryptedFile):
+		other = defaultStorageFile
+	default:
+		return nil
+	}
+	_, err := os.Stat(other)
+	if err == nil {
+		return fmt.Errorf("cannot restore backup of %s while %s exists", backup.Ledger, other)
 	}
-	fmt.Printf("Total expenses: %d", sum)
 	return nil
 }
 
-func isFlagPassed(flags *flag.FlagSet, name string) bool {
-	found := false
-	flags.Visit(func(f *flag.Flag) {
-		if f.Name == name {
-			found = true
+func DoctorCmd(doctorCmd *flag.FlagSet, env *Env) func(tracker *Tracker) error {
+	file := doctorCmd.String("file", defaultStorageFile, "ledger file to check")
+	repair := doctorCmd.Bool("repair", false, "write repaired ledger sorted by ID, moving bad rows to the quarantine file")
+	quarantine := doctorCmd.String("quarantine", "", "file bad rows are appended to on repair, ledger file with .quarantine extension by default")
+
+	return func(_ *Tracker) error {
+		if *quarantine == "" {
+			*quarantine = *file + ".quarantine"
 		}
-	})
-	return found
+
+		data, err := os.ReadFile(*file)
+		if err != nil {
+			fmt.Fprintf(env.Stderr, "error reading ledger: %v\n", err)
+			return err
+		}
+		if isEncryptedFile(*file) {
+			fmt.Fprintln(env.Stderr, "ledger is encrypted, decrypt it to check")
+			return errors.New("ledger is encrypted")
+		}
+
+		records, issues := CheckLedger(data, env.Now())
+		bad, warnings := 0, 0
+		for _, issue := range issues {
+			severity := "warning"
+			if issue.Bad {
+				severity = "error"
+				bad++
+			} else {
+				warnings++
+			}
+			fmt.Fprintf(env.Stdout, "line %d: %s: %s\n", issue.Line, severity, issue.Reason)
+		}
+		if len(issues) == 0 {
+			fmt.Fprintf(env.Stdout, "No issues found in %d records of %s", len(records), *file)
+			return nil
+		}
+		fmt.Fprintf(env.Stdout, "Found %d errors and %d warnings in %s\n", bad, warnings, *file)
+
+		if !*repair {
+			if bad > 0 {
+				fmt.Fprint(env.Stdout, "Run doctor --repair to move bad rows to the quarantine file")
+				return errors.New("ledger has bad rows")
+			}
+			return nil
+		}
+
+		repaired, rows := RepairLedger(records, issues)
+		if len(rows) > 0 {
+			quarantineFile, err := os.OpenFile(*quarantine, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+			if err != nil {
+				fmt.Fprintf(env.Stderr, "error writing quarantine file: %v\n", err)
+				return err
+			}
+			_, err = quarantineFile.Write(rows)
+			closeErr := quarantineFile.Close()
+			if err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				fmt.Fprintf(env.Stderr, "error writing quarantine file: %v\n", err)
+				return err
+			}
+		}
+
+		storage := NewStorageFromFile(*file)
+		storage.SetBackups(env.Backups)
+		storage.SetLastId(max(parseLastId(data), quarantinedLastId(issues)))
+		err = storage.Save(repaired)
+		if err != nil {
+			fmt.Fprintf(env.Stderr, "error writing repaired ledger: %v\n", err)
+			return err
+		}
+
+		fmt.Fprintf(env.Stdout, "Repaired %s: kept %d records, moved %d rows to %s", *file, len(repaired), bad, *quarantine)
+		return nil
+	}
 }
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// completeCommandName is a hidden command called by completion scripts to list values from the ledger.
+const completeCommandName = "__complete"
+
+var completionShells = []string{"bash", "zsh", "fish"}
+
+// flagValues are fixed values offered for flags in completion scripts.
+var flagValues = map[string][]string{
+	"--by":      {string(ByDay), string(ByWeek), string(ByMonth)},
+	"--format":  {ExportCsv, ExportJson, ExportXlsx},
+	"--quarter": {"Q1", "Q2", "Q3", "Q4"},
+}
+
+// ledgerValues are flag values completion scripts query from the ledger with the complete command,
+// command limits the flags to one command when other commands use them for other values.
+type ledgerValues struct {
+	kind    string
+	label   string
+	command string
+	flags   []string
+}
+
+var ledgerFlags = []ledgerValues{
+	{kind: "ids", label: "record ID", flags: []string{"--id"}},
+	{kind: "tags", label: "tag", flags: []string{"--tag", "--any-tag", "--untag"}},
+	{kind: "accounts", label: "account", flags: []string{"--account"}},
+	{kind: "accounts", label: "account", command: "transfer", flags: []string{"--from", "--to"}},
+}
+
+// ledgerValuesOf returns the ledger values completed for the flag of the command.
+func ledgerValuesOf(command, flag string) (ledgerValues, bool) {
+	for _, values := range ledgerFlags {
+		if (values.command == "" || values.command == command) && slices.Contains(values.flags, flag) {
+			return values, true
+		}
+	}
+	return ledgerValues{}, false
+}
+
+// casePattern matches the flag, or the command and the flag for values limited to a command,
+// in the case statements of bash and zsh scripts.
+func (v ledgerValues) casePattern() string {
+	if v.command == "" {
+		return strings.Join(v.flags, "|")
+	}
+	patterns := make([]string, 0, len(v.flags))
+	for _, flag := range v.flags {
+		patterns = append(patterns, fmt.Sprintf("%q", v.command+" "+flag))
+	}
+	return strings.Join(patterns, "|")
+}
+
+// ledgerFlagsFor returns ledger values of all commands or limited to a command.
+func ledgerFlagsFor(limited bool) []ledgerValues {
+	result := make([]ledgerValues, 0, len(ledgerFlags))
+	for _, values := range ledgerFlags {
+		if (values.command != "") == limited {
+			result = append(result, values)
+		}
+	}
+	return result
+}
+
+// fileFlags take file paths.
+var fileFlags = []string{"--file", "--input", "--output", "--quarantine"}
+
+func CompletionCmd(completionCmd *flag.FlagSet, env *Env) func(tracker *Tracker) error {
+	return func(_ *Tracker) error {
+		if completionCmd.NArg() != 1 {
+			completionCmd.Usage()
+			return errors.New("invalid shell")
+		}
+
+		switch completionCmd.Arg(0) {
+		case "bash":
+			return BashCompletion(env.Stdout, Commands, env)
+		case "zsh":
+			return ZshCompletion(env.Stdout, Commands, env)
+		case "fish":
+			return FishCompletion(env.Stdout, Commands, env)
+		default:
+			completionCmd.Usage()
+			return errors.New("invalid shell")
+		}
+	}
+}
+
+// CompleteCmd prints values for dynamic completion, one per line with a tab separated description.
+func CompleteCmd(completeCmd *flag.FlagSet, env *Env) func(tracker *Tracker) error {
+	return func(_ *Tracker) error {
+		if completeCmd.NArg() != 1 || !slices.ContainsFunc(ledgerFlags, func(values ledgerValues) bool {
+			return values.kind == completeCmd.Arg(0)
+		}) {
+			return errors.New("unknown completion")
+		}
+		// completion scripts discard stderr, a passphrase prompt would hang the shell waiting for blind input,
+		// so an encrypted ledger is completed only with the passphrase from the environment
+		env.Passphrase = environmentPassphrase
+		tracker, err := openTracker(env)
+		if err != nil {
+			return err
+		}
+
+		records := tracker.GetAll()
+		switch completeCmd.Arg(0) {
+		case "ids":
+			for _, record := range records {
+				fmt.Fprintf(env.Stdout, "%d\t%s\n", record.Id, strings.ReplaceAll(record.Description, "\n", " "))
+			}
+		case "tags":
+			for _, total := range ComputeTagTotals(records) {
+				fmt.Fprintf(env.Stdout, "%s\t%d records\n", total.Tag, total.Count)
+			}
+		case "accounts":
+			for _, balance := range ComputeAccountBalances(records) {
+				fmt.Fprintf(env.Stdout, "%s\tbalance %d\n", balance.Account, balance.Balance())
+			}
+		}
+		return nil
+	}
+}
+
+func visibleCommands(commands []Command) []Command {
+	visible := make([]Command, 0, len(commands))
+	for _, command := range commands {
+		if !command.Hidden {
+			visible = append(visible, command)
+		}
+	}
+	return visible
+}
+
+func BashCompletion(w io.Writer, commands []Command, env *Env) error {
+	var script strings.Builder
+	commands = visibleCommands(commands)
+
+	names := make([]string, 0, len(commands))
+	for _, command := range commands {
+		names = append(names, command.Name)
+	}
+
+	script.WriteString("# bash completion for expense-tracker\n")
+	script.WriteString("_expense_tracker() {\n")
+	script.WriteString("    local cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	script.WriteString("    if [[ $COMP_CWORD -eq 1 ]]; then\n")
+	fmt.Fprintf(&script, "        COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(names, " "))
+	script.WriteString("        return\n")
+	script.WriteString("    fi\n")
+	bashLedgerValues := func(values ledgerValues) {
+		fmt.Fprintf(&script, "        %s)\n", values.casePattern())
+		fmt.Fprintf(&script, "            COMPREPLY=($(compgen -W \"$(\"${COMP_WORDS[0]}\" %s %s 2>/dev/null | cut -f1)\" -- \"$cur\"))\n", completeCommandName, values.kind)
+		script.WriteString("            return;;\n")
+	}
+	script.WriteString("    case \"${COMP_WORDS[1]} $prev\" in\n")
+	for _, values := range ledgerFlagsFor(true) {
+		bashLedgerValues(values)
+	}
+	script.WriteString("    esac\n")
+	script.WriteString("    case \"$prev\" in\n")
+	for _, values := range ledgerFlagsFor(false) {
+		bashLedgerValues(values)
+	}
+	fmt.Fprintf(&script, "        %s)\n", strings.Join(fileFlags, "|"))
+	script.WriteString("            COMPREPLY=($(compgen -f -- \"$cur\"))\n")
+	script.WriteString("            return;;\n")
+	for _, name := range sortedKeys(flagValues) {
+		fmt.Fprintf(&script, "        %s)\n", name)
+		fmt.Fprintf(&script, "            COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(flagValues[name], " "))
+		script.WriteString("            return;;\n")
+	}
+	script.WriteString("    esac\n")
+	script.WriteString("    case \"${COMP_WORDS[1]}\" in\n")
+	for _, command := range commands {
+		words := append(command.FlagNames(env), command.Args...)
+		if len(words) == 0 {
+			continue
+		}
+		fmt.Fprintf(&script, "        %s)\n", command.Name)
+		fmt.Fprintf(&script, "            COMPREPLY=($(compgen -W %q -- \"$cur\"));;\n", strings.Join(words, " "))
+	}
+	script.WriteString("    esac\n")
+	script.WriteString("}\n")
+	script.WriteString("complete -F _expense_tracker expense-tracker\n")
+
+	_, err := io.WriteString(w, script.String())
+	return err
+}
+
+func ZshCompletion(w io.Writer, commands []Command, env *Env) error {
+	var script strings.Builder
+	commands = visibleCommands(commands)
+
+	script.WriteString("#compdef expense-tracker\n\n")
+	script.WriteString("_expense_tracker() {\n")
+	script.WriteString("    local -a commands values\n")
+	script.WriteString("    commands=(\n")
+	for _, command := range commands {
+		fmt.Fprintf(&script, "        %s\n", zshQuote(command.Name+":"+command.Summary))
+	}
+	script.WriteString("    )\n")
+	script.WriteString("    if (( CURRENT == 2 )); then\n")
+	script.WriteString("        _describe 'command' commands\n")
+	script.WriteString("        return\n")
+	script.WriteString("    fi\n")
+	zshLedgerValues := func(values ledgerValues) {
+		fmt.Fprintf(&script, "        %s)\n", values.casePattern())
+		fmt.Fprintf(&script, "            for line in ${(f)\"$(\"${words[1]}\" %s %s 2>/dev/null)\"}; do\n", completeCommandName, values.kind)
+		script.WriteString("                values+=(\"${line%%$'\\t'*}:${${line#*$'\\t'}//:/\\:}\")\n")
+		script.WriteString("            done\n")
+		fmt.Fprintf(&script, "            _describe %s values\n", zshQuote(values.label))
+		script.WriteString("            return;;\n")
+	}
+	script.WriteString("    case \"${words[2]} ${words[CURRENT-1]}\" in\n")
+	for _, values := range ledgerFlagsFor(true) {
+		zshLedgerValues(values)
+	}
+	script.WriteString("    esac\n")
+	script.WriteString("    case \"${words[CURRENT-1]}\" in\n")
+	for _, values := range ledgerFlagsFor(false) {
+		zshLedgerValues(values)
+	}
+	fmt.Fprintf(&script, "        %s)\n", strings.Join(fileFlags, "|"))
+	script.WriteString("            _files\n")
+	script.WriteString("            return;;\n")
+	for _, name := range sortedKeys(flagValues) {
+		fmt.Fprintf(&script, "        %s)\n", name)
+		fmt.Fprintf(&script, "            compadd -- %s\n", strings.Join(flagValues[name], " "))
+		script.WriteString("            return;;\n")
+	}
+	script.WriteString("    esac\n")
+	script.WriteString("    case \"${words[2]}\" in\n")
+	for _, command := range commands {
+		words := append(command.FlagNames(env), command.Args...)
+		if len(words) == 0 {
+			continue
+		}
+		fmt.Fprintf(&script, "        %s)\n", command.Name)
+		fmt.Fprintf(&script, "            compadd -- %s;;\n", strings.Join(words, " "))
+	}
+	script.WriteString("    esac\n")
+	script.WriteString("}\n\n")
+	script.WriteString("compdef _expense_tracker expense-tracker\n")
+
+	_, err := io.WriteString(w, script.String())
+	return err
+}
+
+func FishCompletion(w io.Writer, commands []Command, env *Env) error {
+	var script strings.Builder
+	commands = visibleCommands(commands)
+
+	script.WriteString("# fish completion for expense-tracker\n")
+	script.WriteString("complete -c expense-tracker -f\n")
+	for _, command := range commands {
+		fmt.Fprintf(&script, "complete -c expense-tracker -n __fish_use_subcommand -a %s -d %s\n", command.Name, fishQuote(command.Summary))
+	}
+	for _, command := range commands {
+		condition := fishQuote("__fish_seen_subcommand_from " + command.Name)
+		for _, name := range command.FlagNames(env) {
+			option := fmt.Sprintf("complete -c expense-tracker -n %s -l %s", condition, strings.TrimPrefix(name, "--"))
+			values, fromLedger := ledgerValuesOf(command.Name, name)
+			switch {
+			case fromLedger:
+				fmt.Fprintf(&script, "%s -x -a %s\n", option, fishQuote(fmt.Sprintf("(expense-tracker %s %s 2>/dev/null)", completeCommandName, values.kind)))
+			case slices.Contains(fileFlags, name):
+				fmt.Fprintf(&script, "%s -r -F\n", option)
+			case flagValues[name] != nil:
+				fmt.Fprintf(&script, "%s -x -a %s\n", option, fishQuote(strings.Join(flagValues[name], " ")))
+			default:
+				fmt.Fprintf(&script, "%s -x\n", option)
+			}
+		}
+		if len(command.Args) > 0 {
+			fmt.Fprintf(&script, "complete -c expense-tracker -n %s -a %s\n", condition, fishQuote(strings.Join(command.Args, " ")))
+		}
+	}
+
+	_, err := io.WriteString(w, script.String())
+	return err
+}
+
+func sortedKeys(values map[string][]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func zshQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompletionScripts(t *testing.T) {
+	tests := []struct {
+		name     string
+		generate func(w io.Writer, commands []Command, env *Env) error
+		want     []string
+	}{
+		{
+			name:     "Bash",
+			generate: BashCompletion,
+			want:     []string{"complete -F _expense_tracker expense-tracker", "add update delete", "__complete ids", `compgen -W "day week month"`, "--amount --description", "--tag|--any-tag|--untag)", `"transfer --from"|"transfer --to")`, "__complete accounts"},
+		},
+		{
+			name:     "Zsh",
+			generate: ZshCompletion,
+			want:     []string{"#compdef expense-tracker", "'add:add a new record'", "__complete ids", "compadd -- csv json xlsx", "_files", "_describe 'tag' values", `"transfer --from"|"transfer --to")`},
+		},
+		{
+			name:     "Fish",
+			generate: FishCompletion,
+			want: []string{"-a add -d 'add a new record'", "-l id -x -a '(expense-tracker __complete ids 2>/dev/null)'", "-l output -r -F", "-a 'bash zsh fish'", "-l any-tag -x -a '(expense-tracker __complete tags 2>/dev/null)'",
+				"'__fish_seen_subcommand_from transfer' -l from -x -a '(expense-tracker __complete accounts 2>/dev/null)'", "'__fish_seen_subcommand_from summary' -l from -x\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var script strings.Builder
+			err := tt.generate(&script, Commands, flagsTestEnv())
+			if err != nil {
+				t.Fatalf("generate() error = %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(script.String(), want) {
+					t.Errorf("script does not contain %q:\n%s", want, script.String())
+				}
+			}
+			if strings.Contains(script.String(), "-a "+completeCommandName) {
+				t.Errorf("script offers hidden command %q", completeCommandName)
+			}
+		})
+	}
+}
+
+func TestCompleteLedgerValues(t *testing.T) {
+	records := []TrackerRecord{
+		{Id: 1, Description: "Hotel", Amount: 100, CreatedAt: time.Date(2024, 1, 15, 12, 0, 0, 0, time.Local), Tags: []string{"business", "trip-lisbon"}, Account: "checking"},
+		{Id: 2, Description: "Taxi", Amount: 20, CreatedAt: time.Date(2024, 1, 16, 12, 0, 0, 0, time.Local), Tags: []string{"business"}},
+	}
+	tests := []struct {
+		kind       string
+		wantCode   int
+		wantStdout string
+	}{
+		{kind: "ids", wantStdout: "1\tHotel\n2\tTaxi\n"},
+		{kind: "tags", wantStdout: "business\t2 records\ntrip-lisbon\t1 records\n"},
+		{kind: "accounts", wantStdout: "checking\tbalance -100\ndefault\tbalance -20\n"},
+		{kind: "payments", wantCode: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			code, stdout, _ := runCli(t, &FakeStorage{records: records}, "", completeCommandName, tt.kind)
+			if code != tt.wantCode || stdout != tt.wantStdout {
+				t.Errorf("complete %s = %d, %q, want %d, %q", tt.kind, code, stdout, tt.wantCode, tt.wantStdout)
+			}
+		})
+	}
+}
+
+func TestCompleteWithoutLedger(t *testing.T) {
+	chdirTemp(t)
+	var out strings.Builder
+	env := &Env{Stdin: strings.NewReader(""), Stdout: &out, Stderr: io.Discard, Now: time.Now, Location: time.Local}
+	env.OpenStorage = func() (TrackerStorage, error) {
+		return openLedger(defaultStorageFile, defaultEncryptedFile, environmentPassphrase, nil)
+	}
+	err := Run([]string{completeCommandName, "ids"}, env)
+	if err != nil || out.String() != "" {
+		t.Errorf("complete ids without ledger = %q, %v, want nothing", out.String(), err)
+	}
+	if _, err := os.Stat(defaultStorageFile); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("completion created the ledger, error = %v", err)
+	}
+}
@@ -1,11 +1,16 @@
 package main
 
 import (
+	"bytes"
 	"encoding/csv"
 	"errors"
+	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -13,99 +18,319 @@ var (
 	invalidCsvLine = errors.New("invalid csv line")
 )
 
+// csvHeader lists columns of the ledger csv, required columns come first. Files are read by their header line,
+// so files written before optional columns were added are still loaded.
+var csvHeader = []string{"Id", "CreatedAt", "Amount", "Description", "Uid", "Tags", "Payee", "Notes", "Attachments", "Kind", "Account", "Transfer"}
+
+var requiredCsvColumns = csvHeader[:4]
+
+// legacyCsvColumns are positions of columns in files without a header line.
+var legacyCsvColumns = csvColumns{"Id": 0, "CreatedAt": 1, "Amount": 2, "Description": 3}
+
+// lastIdComment starts the optional first line of the ledger with the highest ID ever assigned,
+// it is written only when records with the highest IDs were deleted.
+const lastIdComment = "# last-id "
+
 type CsvTrackerStorage struct {
 	filename string
+	backups  *Backups
+	lenient  bool
+	// rows skipped by the last lenient read
+	warnings []LedgerIssue
+	// highest ID ever assigned, kept in the file so IDs of deleted records are not reused
+	lastId RecordId
 }
 
 func NewStorageFromFile(filename string) *CsvTrackerStorage {
 	return &CsvTrackerStorage{filename: filename}
 }
 
+// SetBackups enables snapshots of the previous file before every save, nil disables them.
+func (s *CsvTrackerStorage) SetBackups(backups *Backups) {
+	s.backups = backups
+}
+
+// SetLenient makes reads skip rows that cannot be loaded instead of failing,
+// skipped rows are reported by Warnings.
+func (s *CsvTrackerStorage) SetLenient(lenient bool) {
+	s.lenient = lenient
+}
+
+// Warnings returns rows skipped by the last lenient read.
+func (s *CsvTrackerStorage) Warnings() []LedgerIssue {
+	return s.warnings
+}
+
+// LastId returns the highest ID ever assigned as of the last read or save.
+func (s *CsvTrackerStorage) LastId() RecordId {
+	return s.lastId
+}
+
+// SetLastId raises the highest assigned ID kept on the next save, used when moving records between files.
+func (s *CsvTrackerStorage) SetLastId(id RecordId) {
+	s.lastId = max(s.lastId, id)
+}
+
+// ReadAll returns no records when the file does not exist, it is created by the first save.
 func (s *CsvTrackerStorage) ReadAll() ([]TrackerRecord, error) {
-	var records = make([]TrackerRecord, 0)
-	file, err := os.OpenFile(s.filename, os.O_RDONLY|os.O_CREATE, 0666)
+	data, err := os.ReadFile(s.filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return make([]TrackerRecord, 0), nil
+	}
 	if err != nil {
-		return records, err
+		return make([]TrackerRecord, 0), err
 	}
-	defer file.Close()
-
-	reader := csv.NewReader(file)
-	reader.FieldsPerRecord = 4
-
-	for {
-		parts, err := reader.Read()
+	if !s.lenient {
+		records, lastId, err := readCsv(data)
 		if err != nil {
-			if err == io.EOF {
-				return records, nil
-			}
-			return nil, errors.Join(invalidCsvLine, err)
+			return nil, err
 		}
-		if parts[0] == "Id" {
-			continue
+		s.lastId = lastId
+		return records, nil
+	}
+	records, lastId, warnings := readCsvLenient(data)
+	s.lastId = lastId
+	s.warnings = warnings
+	return records, nil
+}
+
+// readCsv reads records in the ledger csv format and the highest assigned ID, the header line is optional.
+func readCsv(data []byte) ([]TrackerRecord, RecordId, error) {
+	records := make([]TrackerRecord, 0)
+	rows, lastId := scanLedger(data)
+	for _, row := range rows {
+		if row.reason != "" {
+			return nil, 0, fmt.Errorf("%w: line %d: %s", invalidCsvLine, row.line, row.reason)
 		}
+		records = append(records, row.record)
+	}
+	return records, max(lastId, maxRecordId(records)), nil
+}
 
-		record, err := fromCsv(parts)
-		if err != nil {
-			return nil, err
+// readCsvLenient reads records in the ledger csv format skipping rows that cannot be loaded,
+// skipped rows are returned as bad issues.
+func readCsvLenient(data []byte) ([]TrackerRecord, RecordId, []LedgerIssue) {
+	records := make([]TrackerRecord, 0)
+	skipped := make([]LedgerIssue, 0)
+	rows, lastId := scanLedger(data)
+	for _, row := range rows {
+		if row.reason != "" {
+			skipped = append(skipped, LedgerIssue{Line: row.line, Reason: row.reason, Bad: true, Row: row.raw})
+			continue
 		}
-		records = append(records, record)
+		records = append(records, row.record)
 	}
+	return records, max(lastId, maxRecordId(records)), skipped
 }
 
+// Save atomically replaces the file, so a failed write leaves the previous ledger in place.
 func (s *CsvTrackerStorage) Save(records []TrackerRecord) error {
-	file, err := os.OpenFile(s.filename, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0666)
+	lastId := max(s.lastId, maxRecordId(records))
+	var data bytes.Buffer
+	err := writeCsv(&data, records, lastId)
 	if err != nil {
 		return err
 	}
-	writer := csv.NewWriter(file)
 
-	defer func() {
-		writer.Flush()
-		err := file.Close()
+	if s.backups != nil {
+		err = s.backups.Snapshot(s.filename)
 		if err != nil {
-			panic(err)
+			return err
 		}
-	}()
-
-	headers := []string{"Id", "CreatedAt", "Amount", "Description"}
-	err = writer.Write(headers)
+	}
+	err = writeFileAtomic(s.filename, data.Bytes(), 0644)
 	if err != nil {
 		return err
 	}
+	s.lastId = lastId
+	return nil
+}
+
+// writeCsv writes records in the ledger csv format with the header line, optional columns
+// are written only when used by some record. lastId is written only when it is greater than IDs of all records.
+func writeCsv(w io.Writer, records []TrackerRecord, lastId RecordId) error {
+	if lastId > maxRecordId(records) {
+		_, err := fmt.Fprintf(w, "%s%d\n", lastIdComment, lastId)
+		if err != nil {
+			return err
+		}
+	}
+
+	rows := make([][]string, 0, len(records))
+	used := make([]bool, len(csvHeader))
+	for i := range requiredCsvColumns {
+		used[i] = true
+	}
 	for _, record := range records {
-		err := writer.Write(toCsv(record))
+		row := toCsv(record)
+		for i, value := range row {
+			used[i] = used[i] || value != ""
+		}
+		rows = append(rows, row)
+	}
+
+	writer := csv.NewWriter(w)
+	err := writer.Write(usedColumns(csvHeader, used))
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		err := writer.Write(usedColumns(row, used))
 		if err != nil {
 			return err
 		}
 	}
-	return nil
+	writer.Flush()
+	return writer.Error()
 }
 
-func fromCsv(parts []string) (TrackerRecord, error) {
-	if len(parts) != 4 {
-		return TrackerRecord{}, invalidCsvLine
+func usedColumns(row []string, used []bool) []string {
+	values := make([]string, 0, len(row))
+	for i, value := range row {
+		if used[i] {
+			values = append(values, value)
+		}
 	}
-	id, err := strconv.ParseUint(parts[0], 10, 32)
+	return values
+}
+
+// parseLastId returns the highest assigned ID from the first line of the ledger, 0 if there is none.
+func parseLastId(data []byte) RecordId {
+	line, _, _ := bytes.Cut(data, []byte("\n"))
+	value, ok := bytes.CutPrefix(bytes.TrimSuffix(line, []byte("\r")), []byte(lastIdComment))
+	if !ok {
+		return 0
+	}
+	id, err := strconv.ParseUint(string(value), 10, 32)
 	if err != nil {
-		return TrackerRecord{}, errors.Join(invalidCsvLine, err)
+		return 0
+	}
+	return RecordId(id)
+}
+
+func maxRecordId(records []TrackerRecord) RecordId {
+	var id RecordId
+	for _, record := range records {
+		id = max(id, record.Id)
+	}
+	return id
+}
+
+type csvColumns map[string]int
+
+// isCsvHeader reports whether a row is a header line: it starts with the Id column
+// or consists of known column names only.
+func isCsvHeader(parts []string) bool {
+	if len(parts) > 0 && parts[0] == "Id" {
+		return true
+	}
+	for _, part := range parts {
+		if !slices.Contains(csvHeader, part) {
+			return false
+		}
+	}
+	return len(parts) > 0
+}
+
+// newCsvColumns maps names in the header line to positions, columns after Description are optional.
+func newCsvColumns(header []string) (csvColumns, error) {
+	columns := make(csvColumns, len(header))
+	for i, name := range header {
+		if !slices.Contains(csvHeader, name) {
+			return nil, fmt.Errorf("%w: unknown column %q", invalidCsvLine, name)
+		}
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("%w: duplicate column %q", invalidCsvLine, name)
+		}
+		columns[name] = i
+	}
+	for _, name := range requiredCsvColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("%w: missing column %q", invalidCsvLine, name)
+		}
+	}
+	return columns, nil
+}
+
+func fromCsv(columns csvColumns, parts []string) (TrackerRecord, error) {
+	if len(parts) != len(columns) {
+		return TrackerRecord{}, fmt.Errorf("%w: expected %d fields, got %d", invalidCsvLine, len(columns), len(parts))
+	}
+	field := func(name string) string {
+		return parts[columns[name]]
 	}
 
-	createdAt, err := time.Parse(time.RFC3339, parts[1])
+	id, err := strconv.ParseUint(field("Id"), 10, 32)
 	if err != nil {
-		return TrackerRecord{}, errors.Join(invalidCsvLine, err)
+		return TrackerRecord{}, fmt.Errorf("%w: invalid ID %q", invalidCsvLine, field("Id"))
 	}
 
-	amount, err := strconv.ParseUint(parts[2], 10, 32)
+	createdAt, err := time.Parse(time.RFC3339, field("CreatedAt"))
 	if err != nil {
-		return TrackerRecord{}, errors.Join(invalidCsvLine, err)
+		return TrackerRecord{}, fmt.Errorf("%w: invalid date %q", invalidCsvLine, field("CreatedAt"))
 	}
 
-	return TrackerRecord{
+	amount, err := strconv.ParseUint(field("Amount"), 10, 32)
+	if err != nil {
+		return TrackerRecord{}, fmt.Errorf("%w: invalid amount %q", invalidCsvLine, field("Amount"))
+	}
+
+	var uid string
+	if _, ok := columns["Uid"]; ok {
+		uid = field("Uid")
+		if uid != "" && !isUlid(uid) {
+			return TrackerRecord{}, fmt.Errorf("%w: invalid UID %q", invalidCsvLine, uid)
+		}
+	}
+
+	var tags []string
+	if _, ok := columns["Tags"]; ok {
+		// tags are separated by spaces
+		tags, err = tagSet(strings.Fields(field("Tags")))
+		if err != nil {
+			return TrackerRecord{}, fmt.Errorf("%w: invalid tags %q", invalidCsvLine, field("Tags"))
+		}
+	}
+
+	record := TrackerRecord{
 		Id:          RecordId(id),
-		Description: parts[3],
+		Uid:         uid,
+		Description: field("Description"),
 		Amount:      uint(amount),
 		CreatedAt:   createdAt,
-	}, nil
+		Tags:        tags,
+	}
+	if _, ok := columns["Payee"]; ok {
+		record.Payee = field("Payee")
+	}
+	if _, ok := columns["Notes"]; ok {
+		record.Notes = field("Notes")
+	}
+	if _, ok := columns["Attachments"]; ok {
+		record.Attachments, err = parseAttachments(field("Attachments"))
+		if err != nil {
+			return TrackerRecord{}, fmt.Errorf("%w: invalid attachments %q", invalidCsvLine, field("Attachments"))
+		}
+	}
+	if _, ok := columns["Kind"]; ok {
+		record.Kind, err = parseRecordKind(field("Kind"))
+		if err != nil {
+			return TrackerRecord{}, fmt.Errorf("%w: invalid kind %q", invalidCsvLine, field("Kind"))
+		}
+	}
+	if _, ok := columns["Account"]; ok {
+		record.Account, err = normalizeAccount(field("Account"))
+		if err != nil {
+			return TrackerRecord{}, fmt.Errorf("%w: invalid account %q", invalidCsvLine, field("Account"))
+		}
+	}
+	if _, ok := columns["Transfer"]; ok {
+		record.Transfer = field("Transfer")
+		if record.Transfer != "" && !isUlid(record.Transfer) {
+			return TrackerRecord{}, fmt.Errorf("%w: invalid transfer %q", invalidCsvLine, record.Transfer)
+		}
+	}
+	return record, nil
 }
 
 func toCsv(record TrackerRecord) []string {
@@ -114,5 +339,13 @@ func toCsv(record TrackerRecord) []string {
 		record.CreatedAt.Format(time.RFC3339),
 		strconv.FormatUint(uint64(record.Amount), 10),
 		record.Description,
+		record.Uid,
+		strings.Join(record.Tags, " "),
+		record.Payee,
+		record.Notes,
+		formatAttachments(record.Attachments),
+		string(record.Kind),
+		record.Account,
+		record.Transfer,
 	}
 }
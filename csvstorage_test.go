@@ -2,6 +2,7 @@ package main
 
 import (
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
@@ -61,6 +62,169 @@ func TestCsvTrackerStorage_ReadAll(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "ColumnsByHeader",
+			content: "Description,Uid,Id,Amount,CreatedAt\n" +
+				"record1,01ARYZ6S41TSV4RRFFQ69G5FAV,1,100,2024-01-01T01:01:01Z\n" +
+				"record2,,2,200,2024-01-02T02:02:02Z\n",
+			want: []TrackerRecord{
+				{
+					Id:          1,
+					Uid:         "01ARYZ6S41TSV4RRFFQ69G5FAV",
+					CreatedAt:   time.Date(2024, 1, 1, 1, 1, 1, 0, time.UTC),
+					Amount:      100,
+					Description: "record1",
+				},
+				{
+					Id:          2,
+					CreatedAt:   time.Date(2024, 1, 2, 2, 2, 2, 0, time.UTC),
+					Amount:      200,
+					Description: "record2",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "LastIdLine",
+			content: "# last-id 7\nId,CreatedAt,Amount,Description,Uid\n" +
+				"1,2024-01-01T01:01:01Z,100,\"record1\n# not a comment\",\n",
+			want: []TrackerRecord{
+				{
+					Id:          1,
+					CreatedAt:   time.Date(2024, 1, 1, 1, 1, 1, 0, time.UTC),
+					Amount:      100,
+					Description: "record1\n# not a comment",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Tags",
+			content: "Id,CreatedAt,Amount,Description,Tags\n" +
+				"1,2024-01-01T01:01:01Z,100,record1,trip-lisbon  Business\n",
+			want: []TrackerRecord{
+				{
+					Id:          1,
+					CreatedAt:   time.Date(2024, 1, 1, 1, 1, 1, 0, time.UTC),
+					Amount:      100,
+					Description: "record1",
+					Tags:        []string{"business", "trip-lisbon"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "InvalidTags",
+			content: "Id,CreatedAt,Amount,Description,Tags\n" +
+				"1,2024-01-01T01:01:01Z,100,record1,trip/lisbon\n",
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "PayeeAndNotes",
+			content: "Id,CreatedAt,Amount,Description,Notes,Payee\n" +
+				"1,2024-01-01T01:01:01Z,100,record1,\"line1\nline2\",Grocer\n",
+			want: []TrackerRecord{
+				{
+					Id:          1,
+					CreatedAt:   time.Date(2024, 1, 1, 1, 1, 1, 0, time.UTC),
+					Amount:      100,
+					Description: "record1",
+					Payee:       "Grocer",
+					Notes:       "line1\nline2",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Attachments",
+			content: "Id,CreatedAt,Amount,Description,Attachments\n" +
+				"1,2024-01-01T01:01:01Z,100,record1,\"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855 receipt.pdf\ne3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855 hotel bill.pdf\"\n",
+			want: []TrackerRecord{
+				{
+					Id:          1,
+					CreatedAt:   time.Date(2024, 1, 1, 1, 1, 1, 0, time.UTC),
+					Amount:      100,
+					Description: "record1",
+					Attachments: []Attachment{{Name: "receipt.pdf", Key: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"}, {Name: "hotel bill.pdf", Key: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "InvalidAttachments",
+			content: "Id,CreatedAt,Amount,Description,Attachments\n" +
+				"1,2024-01-01T01:01:01Z,100,record1,receipt.pdf\n",
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "Kind",
+			content: "Id,CreatedAt,Amount,Description,Kind\n" +
+				"1,2024-01-01T01:01:01Z,100,record1,income\n" +
+				"2,2024-01-02T02:02:02Z,200,record2,\n" +
+				"3,2024-01-03T03:03:03Z,300,record3,expense\n",
+			want: []TrackerRecord{
+				{Id: 1, CreatedAt: time.Date(2024, 1, 1, 1, 1, 1, 0, time.UTC), Amount: 100, Description: "record1", Kind: KindIncome},
+				{Id: 2, CreatedAt: time.Date(2024, 1, 2, 2, 2, 2, 0, time.UTC), Amount: 200, Description: "record2"},
+				{Id: 3, CreatedAt: time.Date(2024, 1, 3, 3, 3, 3, 0, time.UTC), Amount: 300, Description: "record3"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "AccountAndTransfer",
+			content: "Id,CreatedAt,Amount,Description,Kind,Account,Transfer\n" +
+				"1,2024-01-01T01:01:01Z,100,record1,transfer-out,checking,01HQ3Z8V4M6W2R5T7Y9B1C3D5F\n" +
+				"2,2024-01-02T02:02:02Z,200,record2,,,\n",
+			want: []TrackerRecord{
+				{
+					Id:          1,
+					CreatedAt:   time.Date(2024, 1, 1, 1, 1, 1, 0, time.UTC),
+					Amount:      100,
+					Description: "record1",
+					Kind:        KindTransferOut,
+					Account:     "checking",
+					Transfer:    "01HQ3Z8V4M6W2R5T7Y9B1C3D5F",
+				},
+				{Id: 2, CreatedAt: time.Date(2024, 1, 2, 2, 2, 2, 0, time.UTC), Amount: 200, Description: "record2"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "InvalidAccount",
+			content: "Id,CreatedAt,Amount,Description,Account\n" +
+				"1,2024-01-01T01:01:01Z,100,record1,my cash\n",
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "InvalidKind",
+			content: "Id,CreatedAt,Amount,Description,Kind\n" +
+				"1,2024-01-01T01:01:01Z,100,record1,refund\n",
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "UnknownColumn",
+			content: "Id,CreatedAt,Amount,Description,Category\n" +
+				"1,2024-01-01T01:01:01Z,100,record1,food\n",
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "MissingColumn",
+			content: "Id,CreatedAt,Description\n" +
+				"1,2024-01-01T01:01:01Z,record1\n",
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "InvalidUid",
+			content: "Id,CreatedAt,Amount,Description,Uid\n" +
+				"1,2024-01-01T01:01:01Z,100,record1,42\n",
+			want:    nil,
+			wantErr: true,
+		},
 		{
 			name:    "InvalidFormat",
 			content: "Invalid content",
@@ -111,10 +275,99 @@ func TestCsvTrackerStorage_ReadAll(t *testing.T) {
 	}
 }
 
+func TestCsvTrackerStorage_LastId(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    RecordId
+	}{
+		{name: "Empty", content: "", want: 0},
+		{name: "FromRecords", content: "Id,CreatedAt,Amount,Description\n5,2024-01-01T01:01:01Z,100,record1\n", want: 5},
+		{name: "FromLastIdLine", content: "# last-id 7\nId,CreatedAt,Amount,Description\n5,2024-01-01T01:01:01Z,100,record1\n", want: 7},
+		{name: "LastIdLineOnly", content: "# last-id 7\r\nId,CreatedAt,Amount,Description\r\n", want: 7},
+		{name: "StaleLastIdLine", content: "# last-id 2\nId,CreatedAt,Amount,Description\n5,2024-01-01T01:01:01Z,100,record1\n", want: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewStorageFromFile(filepath.Join(t.TempDir(), "expenses.csv"))
+			if err := os.WriteFile(s.filename, []byte(tt.content), 0666); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+
+			if _, err := s.ReadAll(); err != nil {
+				t.Fatalf("CsvTrackerStorage.ReadAll() error = %v", err)
+			}
+			if got := s.LastId(); got != tt.want {
+				t.Errorf("CsvTrackerStorage.LastId() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCsvTrackerStorage_ReadAllLenient(t *testing.T) {
+	tests := []struct {
+		name         string
+		content      string
+		wantIds      []RecordId
+		wantWarnings []LedgerIssue
+	}{
+		{
+			name:         "Valid",
+			content:      "Id,CreatedAt,Amount,Description\n1,2024-01-01T01:01:01Z,100,record1\n",
+			wantIds:      []RecordId{1},
+			wantWarnings: []LedgerIssue{},
+		},
+		{
+			name: "SkipsBadRows",
+			content: "Id,CreatedAt,Amount,Description\n" +
+				"1,2024-01-01T01:01:01Z,100,record1\n" +
+				"2,2024-01-01111T01:01:01Z,100,record2\n" +
+				"3,2024-01-03T03:03:03Z,300,record3\n" +
+				"4,2024-01-04T04:04:04Z,-125,record4\n",
+			wantIds: []RecordId{1, 3},
+			wantWarnings: []LedgerIssue{
+				{Line: 3, Reason: `invalid date "2024-01-01111T01:01:01Z"`, Bad: true, Row: []byte("2,2024-01-01111T01:01:01Z,100,record2\n")},
+				{Line: 5, Reason: `invalid amount "-125"`, Bad: true, Row: []byte("4,2024-01-04T04:04:04Z,-125,record4\n")},
+			},
+		},
+		{
+			name:         "KeepsDuplicates",
+			content:      "1,2024-01-01T01:01:01Z,100,record1\n1,2024-01-02T02:02:02Z,0,record2\n",
+			wantIds:      []RecordId{1, 1},
+			wantWarnings: []LedgerIssue{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewStorageFromFile(filepath.Join(t.TempDir(), "expenses.csv"))
+			s.SetLenient(true)
+			if err := os.WriteFile(s.filename, []byte(tt.content), 0666); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+
+			got, err := s.ReadAll()
+			if err != nil {
+				t.Fatalf("CsvTrackerStorage.ReadAll() error = %v", err)
+			}
+			ids := make([]RecordId, 0, len(got))
+			for _, record := range got {
+				ids = append(ids, record.Id)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIds) {
+				t.Errorf("CsvTrackerStorage.ReadAll() IDs = %v, want %v", ids, tt.wantIds)
+			}
+			if !reflect.DeepEqual(s.Warnings(), tt.wantWarnings) {
+				t.Errorf("CsvTrackerStorage.Warnings() = %+v, want %+v", s.Warnings(), tt.wantWarnings)
+			}
+		})
+	}
+}
+
 func TestCsvTrackerStorage_Save(t *testing.T) {
 	tests := []struct {
 		name     string
 		records  []TrackerRecord
+		lastId   RecordId
 		expected string
 		wantErr  bool
 	}{
@@ -169,10 +422,110 @@ func TestCsvTrackerStorage_Save(t *testing.T) {
 			expected: "Id,CreatedAt,Amount,Description\n1,2024-01-01T01:01:01Z,100,\"long, lorem ipsum\"\n",
 			wantErr:  false,
 		},
+		{
+			name: "OriginalOffset",
+			records: []TrackerRecord{
+				{
+					Id:          1,
+					CreatedAt:   time.Date(2024, 1, 31, 23, 30, 0, 0, time.FixedZone("UTC-5", -5*60*60)),
+					Amount:      100,
+					Description: "record1",
+				},
+			},
+			expected: "Id,CreatedAt,Amount,Description\n1,2024-01-31T23:30:00-05:00,100,record1\n",
+			wantErr:  false,
+		},
+		{
+			name: "WithUid",
+			records: []TrackerRecord{
+				{
+					Id:          1,
+					Uid:         "01ARYZ6S41TSV4RRFFQ69G5FAV",
+					CreatedAt:   time.Date(2024, 1, 1, 1, 1, 1, 0, time.UTC),
+					Amount:      100,
+					Description: "record1",
+				},
+			},
+			expected: "Id,CreatedAt,Amount,Description,Uid\n1,2024-01-01T01:01:01Z,100,record1,01ARYZ6S41TSV4RRFFQ69G5FAV\n",
+			wantErr:  false,
+		},
+		{
+			name: "WithTags",
+			records: []TrackerRecord{
+				{
+					Id:          1,
+					CreatedAt:   time.Date(2024, 1, 1, 1, 1, 1, 0, time.UTC),
+					Amount:      100,
+					Description: "record1",
+					Tags:        []string{"business", "trip-lisbon"},
+				},
+				{
+					Id:          2,
+					CreatedAt:   time.Date(2024, 1, 2, 2, 2, 2, 0, time.UTC),
+					Amount:      200,
+					Description: "record2",
+				},
+			},
+			expected: "Id,CreatedAt,Amount,Description,Tags\n1,2024-01-01T01:01:01Z,100,record1,business trip-lisbon\n2,2024-01-02T02:02:02Z,200,record2,\n",
+			wantErr:  false,
+		},
+		{
+			name: "WithPayeeAndNotes",
+			records: []TrackerRecord{
+				{
+					Id:          1,
+					CreatedAt:   time.Date(2024, 1, 1, 1, 1, 1, 0, time.UTC),
+					Amount:      100,
+					Description: "record1",
+					Payee:       "Cafe, Central",
+					Notes:       "line1\nline2",
+				},
+			},
+			expected: "Id,CreatedAt,Amount,Description,Payee,Notes\n1,2024-01-01T01:01:01Z,100,record1,\"Cafe, Central\",\"line1\nline2\"\n",
+			wantErr:  false,
+		},
+		{
+			name: "WithAttachments",
+			records: []TrackerRecord{
+				{
+					Id:          1,
+					CreatedAt:   time.Date(2024, 1, 1, 1, 1, 1, 0, time.UTC),
+					Amount:      100,
+					Description: "record1",
+					Attachments: []Attachment{{Name: "receipt.pdf", Key: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"}},
+				},
+			},
+			expected: "Id,CreatedAt,Amount,Description,Attachments\n1,2024-01-01T01:01:01Z,100,record1,e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855 receipt.pdf\n",
+			wantErr:  false,
+		},
+		{
+			name: "WithIncome",
+			records: []TrackerRecord{
+				{Id: 1, CreatedAt: time.Date(2024, 1, 1, 1, 1, 1, 0, time.UTC), Amount: 100, Description: "record1", Kind: KindIncome},
+				{Id: 2, CreatedAt: time.Date(2024, 1, 2, 2, 2, 2, 0, time.UTC), Amount: 200, Description: "record2"},
+			},
+			expected: "Id,CreatedAt,Amount,Description,Kind\n1,2024-01-01T01:01:01Z,100,record1,income\n2,2024-01-02T02:02:02Z,200,record2,\n",
+			wantErr:  false,
+		},
+		{
+			name: "LastIdAfterDelete",
+			records: []TrackerRecord{
+				{
+					Id:          1,
+					CreatedAt:   time.Date(2024, 1, 1, 1, 1, 1, 0, time.UTC),
+					Amount:      100,
+					Description: "record1",
+				},
+			},
+			lastId:   3,
+			expected: "# last-id 3\nId,CreatedAt,Amount,Description\n1,2024-01-01T01:01:01Z,100,record1\n",
+			wantErr:  false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			s := NewStorageFromFile("trackerstorage_test.csv")
+			s.SetLastId(tt.lastId)
 			// Clean up the file after each test.
 			defer os.Remove(s.filename)
 
@@ -190,3 +543,28 @@ func TestCsvTrackerStorage_Save(t *testing.T) {
 		})
 	}
 }
+
+func TestCsvTrackerStorage_SaveFailure(t *testing.T) {
+	dir := t.TempDir()
+	ledger := filepath.Join(dir, "expenses.csv")
+	previous := "Id,CreatedAt,Amount,Description\n1,2024-01-01T01:01:01Z,100,record1\n"
+	if err := os.WriteFile(ledger, []byte(previous), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// the ledger file in place of a directory makes the save fail
+	s := NewStorageFromFile(filepath.Join(dir, "expenses.csv", "nested.csv"))
+	records := []TrackerRecord{{Id: 5, CreatedAt: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), Amount: 1, Description: "new"}}
+	if err := s.Save(records); err == nil {
+		t.Fatal("Save() error = nil, want error")
+	}
+	if s.LastId() != 0 {
+		t.Errorf("LastId() = %d after failed save, want 0", s.LastId())
+	}
+	if data, err := os.ReadFile(ledger); err != nil || string(data) != previous {
+		t.Errorf("ledger after failed save = %q, %v", data, err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("files after failed save = %v, want only the ledger", entries)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LedgerIssue is a problem found in a row of the ledger file.
+type LedgerIssue struct {
+	Line   int
+	Reason string
+	// bad rows cannot be loaded and are quarantined on repair, other issues are warnings
+	Bad bool
+	// raw content of the row including the line break
+	Row []byte
+	// ID of the row if it can be read, 0 otherwise
+	Id RecordId
+}
+
+// ledgerRow is a row of the ledger file, reason is set when the row cannot be loaded.
+type ledgerRow struct {
+	line   int
+	raw    []byte
+	record TrackerRecord
+	reason string
+	// ID of a row that cannot be loaded if its ID field is valid
+	id RecordId
+}
+
+// scanLedger splits the ledger csv into rows without stopping at rows that cannot be loaded
+// and returns the highest assigned ID from the first line. Header lines select the columns of following rows.
+func scanLedger(data []byte) ([]ledgerRow, RecordId) {
+	rows := make([]ledgerRow, 0)
+	columns := legacyCsvColumns
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+	for {
+		start := reader.InputOffset()
+		parts, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, parseLastId(data)
+		}
+		raw := data[start:reader.InputOffset()]
+
+		var parseError *csv.ParseError
+		if errors.As(err, &parseError) {
+			rows = append(rows, ledgerRow{line: parseError.StartLine, raw: raw, reason: parseError.Err.Error()})
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+
+		var record TrackerRecord
+		if isCsvHeader(parts) {
+			columns, err = newCsvColumns(parts)
+			if err == nil {
+				continue
+			}
+			columns = legacyCsvColumns
+		} else {
+			record, err = fromCsv(columns, parts)
+		}
+		if err != nil {
+			rows = append(rows, ledgerRow{line: line, raw: raw, reason: strings.TrimPrefix(err.Error(), invalidCsvLine.Error()+": "), id: rowId(columns, parts)})
+			continue
+		}
+		rows = append(rows, ledgerRow{line: line, raw: raw, record: record})
+	}
+}
+
+// rowId returns the ID field of a row that cannot be loaded, 0 if it is missing or invalid.
+func rowId(columns csvColumns, parts []string) RecordId {
+	index, ok := columns["Id"]
+	if !ok || index >= len(parts) {
+		return 0
+	}
+	id, err := strconv.ParseUint(parts[index], 10, 32)
+	if err != nil {
+		return 0
+	}
+	return RecordId(id)
+}
+
+// CheckLedger scans the ledger csv reporting malformed rows, duplicate IDs, zero amounts,
+// non-monotonic IDs and dates after now. Records are the rows that can be loaded, in file order.
+func CheckLedger(data []byte, now time.Time) ([]TrackerRecord, []LedgerIssue) {
+	records := make([]TrackerRecord, 0)
+	issues := make([]LedgerIssue, 0)
+	firstLines := make(map[RecordId]int)
+	var lastId RecordId
+
+	rows, _ := scanLedger(data)
+	for _, row := range rows {
+		if row.reason != "" {
+			issues = append(issues, LedgerIssue{Line: row.line, Reason: row.reason, Bad: true, Row: row.raw, Id: row.id})
+			continue
+		}
+		record := row.record
+		if first, ok := firstLines[record.Id]; ok {
+			issues = append(issues, LedgerIssue{Line: row.line, Reason: fmt.Sprintf("duplicate ID %d, first on line %d", record.Id, first), Bad: true, Row: row.raw, Id: record.Id})
+			continue
+		}
+		if record.Amount == 0 {
+			issues = append(issues, LedgerIssue{Line: row.line, Reason: "zero amount", Bad: true, Row: row.raw, Id: record.Id})
+			continue
+		}
+
+		if record.Id <= lastId {
+			issues = append(issues, LedgerIssue{Line: row.line, Reason: fmt.Sprintf("ID %d is not greater than previous ID %d", record.Id, lastId), Row: row.raw, Id: record.Id})
+		}
+		if record.CreatedAt.After(now) {
+			issues = append(issues, LedgerIssue{Line: row.line, Reason: fmt.Sprintf("date %s is in the future", record.CreatedAt.Format(time.RFC3339)), Row: row.raw, Id: record.Id})
+		}
+		firstLines[record.Id] = row.line
+		lastId = max(lastId, record.Id)
+		records = append(records, record)
+	}
+	return records, issues
+}
+
+// RepairLedger returns records of a checked ledger sorted by ID and the csv content of bad rows.
+// IDs of bad rows stay assigned, see quarantinedLastId.
+func RepairLedger(records []TrackerRecord, issues []LedgerIssue) ([]TrackerRecord, []byte) {
+	repaired := slices.Clone(records)
+	slices.SortStableFunc(repaired, func(a, b TrackerRecord) int {
+		return cmp.Compare(a.Id, b.Id)
+	})
+
+	var quarantine bytes.Buffer
+	for _, issue := range issues {
+		if !issue.Bad {
+			continue
+		}
+		quarantine.Write(issue.Row)
+		if !bytes.HasSuffix(issue.Row, []byte("\n")) {
+			quarantine.WriteByte('\n')
+		}
+	}
+	return repaired, quarantine.Bytes()
+}
+
+// quarantinedLastId returns the highest ID of bad rows, kept in the repaired ledger so the IDs are not reused.
+func quarantinedLastId(issues []LedgerIssue) RecordId {
+	var lastId RecordId
+	for _, issue := range issues {
+		if issue.Bad {
+			lastId = max(lastId, issue.Id)
+		}
+	}
+	return lastId
+}
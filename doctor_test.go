@@ -0,0 +1,95 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCheckLedger(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	header := "Id,CreatedAt,Amount,Description\n"
+
+	tests := []struct {
+		name       string
+		data       string
+		wantIds    []RecordId
+		wantIssues []LedgerIssue
+	}{
+		{
+			name:       "Valid",
+			data:       header + "1,2024-01-01T00:00:00Z,20,Lunch\n2,2024-01-02T00:00:00Z,30,Dinner\n",
+			wantIds:    []RecordId{1, 2},
+			wantIssues: []LedgerIssue{},
+		},
+		{
+			name:    "MalformedRows",
+			data:    header + "1,2024-01-01T00:00:00Z,20,Lunch\n2,yesterday,30,Dinner\n3,2024-01-03T00:00:00Z,abc,Coffee\n4,2024-01-04T00:00:00Z\n",
+			wantIds: []RecordId{1},
+			wantIssues: []LedgerIssue{
+				{Line: 3, Reason: `invalid date "yesterday"`, Bad: true, Row: []byte("2,yesterday,30,Dinner\n"), Id: 2},
+				{Line: 4, Reason: `invalid amount "abc"`, Bad: true, Row: []byte("3,2024-01-03T00:00:00Z,abc,Coffee\n"), Id: 3},
+				{Line: 5, Reason: "expected 4 fields, got 2", Bad: true, Row: []byte("4,2024-01-04T00:00:00Z\n"), Id: 4},
+			},
+		},
+		{
+			name:    "ParseErrorAndMultilineRow",
+			data:    header + "1,2024-01-01T00:00:00Z,20,\"Lunch\nwith team\"\n2,2024-01-02T00:00:00Z,30,Din\"ner\n3,2024-01-03T00:00:00Z,5,Coffee",
+			wantIds: []RecordId{1, 3},
+			wantIssues: []LedgerIssue{
+				{Line: 4, Reason: `bare " in non-quoted-field`, Bad: true, Row: []byte("2,2024-01-02T00:00:00Z,30,Din\"ner\n")},
+			},
+		},
+		{
+			name:    "DuplicateAndZeroAmount",
+			data:    header + "1,2024-01-01T00:00:00Z,20,Lunch\n1,2024-01-02T00:00:00Z,30,Dinner\n2,2024-01-03T00:00:00Z,0,Coffee\n",
+			wantIds: []RecordId{1},
+			wantIssues: []LedgerIssue{
+				{Line: 3, Reason: "duplicate ID 1, first on line 2", Bad: true, Row: []byte("1,2024-01-02T00:00:00Z,30,Dinner\n"), Id: 1},
+				{Line: 4, Reason: "zero amount", Bad: true, Row: []byte("2,2024-01-03T00:00:00Z,0,Coffee\n"), Id: 2},
+			},
+		},
+		{
+			name:    "Warnings",
+			data:    header + "3,2024-01-01T00:00:00Z,20,Lunch\n2,2024-01-02T00:00:00Z,30,Dinner\n4,2024-07-01T00:00:00Z,5,Coffee\n",
+			wantIds: []RecordId{3, 2, 4},
+			wantIssues: []LedgerIssue{
+				{Line: 3, Reason: "ID 2 is not greater than previous ID 3", Row: []byte("2,2024-01-02T00:00:00Z,30,Dinner\n"), Id: 2},
+				{Line: 4, Reason: "date 2024-07-01T00:00:00Z is in the future", Row: []byte("4,2024-07-01T00:00:00Z,5,Coffee\n"), Id: 4},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, issues := CheckLedger([]byte(tt.data), now)
+			ids := make([]RecordId, 0, len(records))
+			for _, record := range records {
+				ids = append(ids, record.Id)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIds) {
+				t.Errorf("CheckLedger() record IDs = %v, want %v", ids, tt.wantIds)
+			}
+			if !reflect.DeepEqual(issues, tt.wantIssues) {
+				t.Errorf("CheckLedger() issues = %+v, want %+v", issues, tt.wantIssues)
+			}
+		})
+	}
+}
+
+func TestRepairLedger(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	data := "Id,CreatedAt,Amount,Description\n3,2024-01-01T00:00:00Z,20,Lunch\n2,2024-01-02T00:00:00Z,0,Dinner\n1,2024-01-03T00:00:00Z,5,Coffee\n4,2024-01-04T00:00:00Z,x,Tea"
+
+	records, quarantine := RepairLedger(CheckLedger([]byte(data), now))
+	var ids []RecordId
+	for _, record := range records {
+		ids = append(ids, record.Id)
+	}
+	if want := []RecordId{1, 3}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("RepairLedger() record IDs = %v, want %v", ids, want)
+	}
+	if want := "2,2024-01-02T00:00:00Z,0,Dinner\n4,2024-01-04T00:00:00Z,x,Tea\n"; string(quarantine) != want {
+		t.Errorf("RepairLedger() quarantine = %q, want %q", quarantine, want)
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+var (
+	invalidEncryptedFile = errors.New("invalid encrypted ledger file")
+	wrongPassphrase      = errors.New("wrong passphrase or corrupted ledger")
+)
+
+// Encrypted ledger file layout, the header is authenticated together with the ciphertext:
+// magic, scrypt log2(N), r and p, salt, nonce, AES-256-GCM encrypted csv.
+const (
+	encryptedMagic     = "ETENC1"
+	encryptedSaltLen   = 16
+	encryptedHeaderLen = len(encryptedMagic) + 3 + encryptedSaltLen
+)
+
+type scryptParams struct {
+	logN, r, p byte
+}
+
+// defaultScryptParams use 32 MiB of memory, recommended for interactive logins.
+var defaultScryptParams = scryptParams{logN: 15, r: 8, p: 1}
+
+// Limits of parameters read from a file header, they are checked before the header is authenticated
+// so a crafted file must not make key derivation exhaust memory or time. Memory is 128·r·N bytes,
+// time grows with memory times p passes.
+const (
+	maxScryptMemory = 256 << 20
+	maxScryptWork   = 4 * maxScryptMemory
+)
+
+func (p scryptParams) withinLimits() bool {
+	if p.logN < 1 || p.logN > 24 || p.r < 1 || p.p < 1 {
+		return false
+	}
+	memory := uint64(128) * uint64(p.r) << p.logN
+	return memory <= maxScryptMemory && memory*uint64(p.p) <= maxScryptWork
+}
+
+// EncryptedTrackerStorage keeps the ledger in csv format encrypted with AES-GCM,
+// the key is derived from a passphrase with scrypt.
+type EncryptedTrackerStorage struct {
+	filename   string
+	passphrase string
+	params     scryptParams
+	// header and key of the last read or written file, reused to avoid deriving the key on every save
+	header  []byte
+	key     []byte
+	backups *Backups
+	// highest ID ever assigned, kept in the file so IDs of deleted records are not reused
+	lastId RecordId
+}
+
+func NewEncryptedStorage(filename, passphrase string) *EncryptedTrackerStorage {
+	return &EncryptedTrackerStorage{filename: filename, passphrase: passphrase, params: defaultScryptParams}
+}
+
+// SetBackups enables snapshots of the previous encrypted file before every save, nil disables them.
+func (s *EncryptedTrackerStorage) SetBackups(backups *Backups) {
+	s.backups = backups
+}
+
+// LastId returns the highest ID ever assigned as of the last read or save.
+func (s *EncryptedTrackerStorage) LastId() RecordId {
+	return s.lastId
+}
+
+// SetLastId raises the highest assigned ID kept on the next save, used when moving records between files.
+func (s *EncryptedTrackerStorage) SetLastId(id RecordId) {
+	s.lastId = max(s.lastId, id)
+}
+
+func (s *EncryptedTrackerStorage) ReadAll() ([]TrackerRecord, error) {
+	data, err := os.ReadFile(s.filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return make([]TrackerRecord, 0), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, header, key, err := decryptLedger(data, s.passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	records, lastId, err := readCsv(plaintext)
+	if err != nil {
+		return nil, err
+	}
+	s.header = bytes.Clone(header)
+	s.key = key
+	s.lastId = lastId
+	return records, nil
+}
+
+// decryptLedger decrypts content of an encrypted ledger file, also used for encrypted backups.
+func decryptLedger(data []byte, passphrase string) (plaintext, header, key []byte, err error) {
+	if len(data) < encryptedHeaderLen || string(data[:len(encryptedMagic)]) != encryptedMagic {
+		return nil, nil, nil, invalidEncryptedFile
+	}
+	header = data[:encryptedHeaderLen]
+	params := scryptParams{logN: header[len(encryptedMagic)], r: header[len(encryptedMagic)+1], p: header[len(encryptedMagic)+2]}
+	salt := header[len(encryptedMagic)+3:]
+	if !params.withinLimits() {
+		return nil, nil, nil, invalidEncryptedFile
+	}
+
+	key, err = deriveKey(passphrase, salt, params)
+	if err != nil {
+		return nil, nil, nil, errors.Join(invalidEncryptedFile, err)
+	}
+	aead, err := newAead(key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	rest := data[encryptedHeaderLen:]
+	if len(rest) < aead.NonceSize() {
+		return nil, nil, nil, invalidEncryptedFile
+	}
+	plaintext, err = aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], header)
+	if err != nil {
+		return nil, nil, nil, wrongPassphrase
+	}
+	return plaintext, header, key, nil
+}
+
+// Save encrypts records with a fresh nonce and atomically replaces the file.
+func (s *EncryptedTrackerStorage) Save(records []TrackerRecord) error {
+	lastId := max(s.lastId, maxRecordId(records))
+	var plaintext bytes.Buffer
+	err := writeCsv(&plaintext, records, lastId)
+	if err != nil {
+		return err
+	}
+
+	data, err := s.Seal(plaintext.Bytes())
+	if err != nil {
+		return err
+	}
+	if s.backups != nil {
+		err = s.backups.Snapshot(s.filename)
+		if err != nil {
+			return err
+		}
+	}
+	err = writeFileAtomic(s.filename, data, 0600)
+	if err != nil {
+		return err
+	}
+	s.lastId = lastId
+	return nil
+}
+
+// Seal encrypts plaintext csv into the encrypted file format with the key of the storage and a fresh nonce,
+// also used to encrypt backups of a plaintext ledger.
+func (s *EncryptedTrackerStorage) Seal(plaintext []byte) ([]byte, error) {
+	if s.key == nil {
+		err := s.newKey()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	aead, err := newAead(s.key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	data := append(bytes.Clone(s.header), nonce...)
+	return aead.Seal(data, nonce, plaintext, s.header), nil
+}
+
+// newKey generates a new salt and derives the key for a new file.
+func (s *EncryptedTrackerStorage) newKey() error {
+	salt := make([]byte, encryptedSaltLen)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return err
+	}
+	key, err := deriveKey(s.passphrase, salt, s.params)
+	if err != nil {
+		return err
+	}
+
+	header := append([]byte(encryptedMagic), s.params.logN, s.params.r, s.params.p)
+	s.header = append(header, salt...)
+	s.key = key
+	return nil
+}
+
+func deriveKey(passphrase string, salt []byte, params scryptParams) ([]byte, error) {
+	if params.logN >= 32 {
+		return nil, invalidScryptParams
+	}
+	return scrypt([]byte(passphrase), salt, 1<<params.logN, int(params.r), int(params.p), 32)
+}
+
+func newAead(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// isEncryptedFile reports whether the file starts with the encrypted ledger magic.
+func isEncryptedFile(filename string) bool {
+	file, err := os.Open(filename)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	magic := make([]byte, len(encryptedMagic))
+	n, _ := file.Read(magic)
+	return string(magic[:n]) == encryptedMagic
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory and renames it over filename.
+func writeFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	file, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Chmod(perm)
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	closeErr := file.Close()
+	if err != nil {
+		return err
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+	return os.Rename(file.Name(), filename)
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useFastScrypt lowers the key derivation cost for the duration of the test.
+func useFastScrypt(t *testing.T) {
+	t.Helper()
+	params := defaultScryptParams
+	defaultScryptParams = scryptParams{logN: 4, r: 1, p: 1}
+	t.Cleanup(func() {
+		defaultScryptParams = params
+	})
+}
+
+func TestEncryptedTrackerStorage_RoundTrip(t *testing.T) {
+	useFastScrypt(t)
+	filename := filepath.Join(t.TempDir(), "expenses.csv.enc")
+	want := []TrackerRecord{
+		{Id: 1, Description: "Lunch, with friends", Amount: 20, CreatedAt: time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)},
+		{Id: 2, Description: "Dinner", Amount: 30, CreatedAt: time.Date(2024, 2, 15, 19, 0, 0, 0, time.UTC)},
+	}
+
+	storage := NewEncryptedStorage(filename, "secret")
+	records, err := storage.ReadAll()
+	if err != nil || len(records) != 0 {
+		t.Fatalf("ReadAll() of missing file = %v, %v, want no records", records, err)
+	}
+	err = storage.Save(want)
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if !strings.HasPrefix(string(data), encryptedMagic) || strings.Contains(string(data), "Lunch") {
+		t.Errorf("file is not encrypted: %q", data)
+	}
+
+	got, err := NewEncryptedStorage(filename, "secret").ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadAll() = %v, want %v", got, want)
+	}
+}
+
+func TestEncryptedTrackerStorage_SaveKeepsSaltAndChangesNonce(t *testing.T) {
+	useFastScrypt(t)
+	filename := filepath.Join(t.TempDir(), "expenses.csv.enc")
+	storage := NewEncryptedStorage(filename, "secret")
+
+	err := storage.Save(testRecords())
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	first, _ := os.ReadFile(filename)
+	err = storage.Save(testRecords())
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	second, _ := os.ReadFile(filename)
+
+	if string(first[:encryptedHeaderLen]) != string(second[:encryptedHeaderLen]) {
+		t.Errorf("header changed between saves")
+	}
+	if string(first[encryptedHeaderLen:]) == string(second[encryptedHeaderLen:]) {
+		t.Errorf("ciphertext did not change between saves, nonce is reused")
+	}
+}
+
+func TestEncryptedTrackerStorage_ReadErrors(t *testing.T) {
+	useFastScrypt(t)
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.enc")
+	err := NewEncryptedStorage(valid, "secret").Save(testRecords())
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	data, _ := os.ReadFile(valid)
+
+	tampered := []byte(string(data))
+	tampered[len(tampered)-1] ^= 1
+	tamperedHeader := []byte(string(data))
+	tamperedHeader[len(encryptedMagic)+3] ^= 1
+	// log2(N) 24 with r 255 would need hundreds of gigabytes
+	hugeParams := []byte(string(data))
+	copy(hugeParams[len(encryptedMagic):], []byte{24, 255, 1})
+	// each parameter is moderate, together they would need 4 GiB
+	largeParams := []byte(string(data))
+	copy(largeParams[len(encryptedMagic):], []byte{20, 32, 1})
+	// 256 MiB passed 16 times
+	manyPasses := []byte(string(data))
+	copy(manyPasses[len(encryptedMagic):], []byte{18, 8, 16})
+	zeroParams := []byte(string(data))
+	copy(zeroParams[len(encryptedMagic):], []byte{15, 0, 1})
+
+	tests := []struct {
+		name       string
+		content    []byte
+		passphrase string
+		wantErr    error
+	}{
+		{name: "WrongPassphrase", content: data, passphrase: "wrong", wantErr: wrongPassphrase},
+		{name: "TamperedCiphertext", content: tampered, passphrase: "secret", wantErr: wrongPassphrase},
+		{name: "TamperedSalt", content: tamperedHeader, passphrase: "secret", wantErr: wrongPassphrase},
+		{name: "Plaintext", content: []byte("Id,CreatedAt,Amount,Description\n"), passphrase: "secret", wantErr: invalidEncryptedFile},
+		{name: "Truncated", content: data[:encryptedHeaderLen+4], passphrase: "secret", wantErr: invalidEncryptedFile},
+		{name: "HugeScryptParams", content: hugeParams, passphrase: "secret", wantErr: invalidEncryptedFile},
+		{name: "LargeScryptParams", content: largeParams, passphrase: "secret", wantErr: invalidEncryptedFile},
+		{name: "ManyScryptPasses", content: manyPasses, passphrase: "secret", wantErr: invalidEncryptedFile},
+		{name: "ZeroScryptParams", content: zeroParams, passphrase: "secret", wantErr: invalidEncryptedFile},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(dir, tt.name)
+			err := os.WriteFile(filename, tt.content, 0600)
+			if err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+			_, err = NewEncryptedStorage(filename, tt.passphrase).ReadAll()
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ReadAll() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOpenLedger(t *testing.T) {
+	useFastScrypt(t)
+	dir := t.TempDir()
+	csvFile := filepath.Join(dir, "expenses.csv")
+	encryptedFile := filepath.Join(dir, "expenses.csv.enc")
+	passphrase := func(_ string) (string, error) {
+		return "secret", nil
+	}
+
+	storage, err := openLedger(csvFile, encryptedFile, passphrase, nil)
+	if _, ok := storage.(*CsvTrackerStorage); err != nil || !ok {
+		t.Errorf("openLedger() without encrypted file = %T, %v, want csv storage", storage, err)
+	}
+
+	err = NewEncryptedStorage(encryptedFile, "secret").Save(testRecords())
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	storage, err = openLedger(csvFile, encryptedFile, passphrase, nil)
+	if _, ok := storage.(*EncryptedTrackerStorage); err != nil || !ok {
+		t.Errorf("openLedger() with encrypted file = %T, %v, want encrypted storage", storage, err)
+	}
+
+	err = os.WriteFile(csvFile, nil, 0600)
+	if err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	_, err = openLedger(csvFile, encryptedFile, passphrase, nil)
+	if err == nil {
+		t.Errorf("openLedger() with both files error = nil, want error")
+	}
+}
+
+func TestScryptParamsWithinLimits(t *testing.T) {
+	tests := []struct {
+		params scryptParams
+		want   bool
+	}{
+		{params: scryptParams{logN: 15, r: 8, p: 1}, want: true},
+		{params: scryptParams{logN: 18, r: 8, p: 4}, want: true},
+		{params: scryptParams{logN: 18, r: 8, p: 5}, want: false},
+		{params: scryptParams{logN: 19, r: 8, p: 1}, want: false},
+		{params: scryptParams{logN: 20, r: 32, p: 1}, want: false},
+		{params: scryptParams{logN: 255, r: 1, p: 1}, want: false},
+		{params: scryptParams{logN: 0, r: 1, p: 1}, want: false},
+	}
+	for _, tt := range tests {
+		if got := tt.params.withinLimits(); got != tt.want {
+			t.Errorf("%+v.withinLimits() = %v, want %v", tt.params, got, tt.want)
+		}
+	}
+}
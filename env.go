@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+)
+
+const (
+	defaultStorageFile   = "./expenses.csv"
+	defaultEncryptedFile = "./expenses.csv.enc"
+	defaultBackupDir     = "./backups"
+	// attachments are kept next to the ledger
+	defaultAttachmentsDir = "./attachments"
+	// backupsVariable set to off disables backups before saves
+	backupsVariable = "EXPENSE_TRACKER_BACKUPS"
+)
+
+// Env is the environment commands run in, replaced in tests to capture output,
+// feed input, fix the current time and use in-memory storage.
+type Env struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	Now    Clock
+	// Location is the reporting timezone dates are shown and periods are calculated in
+	Location *time.Location
+	// OpenStorage returns storage the tracker is loaded from
+	OpenStorage func() (TrackerStorage, error)
+	// Backups snapshots the ledger before saves, nil when backups are disabled
+	Backups *Backups
+	// Passphrase returns the passphrase of the encrypted ledger, prompt is shown when it is asked interactively
+	Passphrase func(prompt string) (string, error)
+	// Attachments keeps files attached to records, nil when attachments are not available
+	Attachments AttachmentStore
+}
+
+// DefaultEnv uses standard streams, the system clock and the ledger in the working directory,
+// encrypted if the encrypted ledger file exists.
+func DefaultEnv() *Env {
+	env := &Env{
+		Stdin:       os.Stdin,
+		Stdout:      os.Stdout,
+		Stderr:      os.Stderr,
+		Now:         time.Now,
+		Location:    time.Local,
+		Attachments: NewDirAttachmentStore(defaultAttachmentsDir),
+	}
+	if os.Getenv(backupsVariable) != "off" {
+		env.Backups = NewBackups(defaultBackupDir, defaultBackupRetention, time.Now)
+	}
+	env.Passphrase = func(prompt string) (string, error) {
+		return readPassphrase(env.Stdin, env.Stderr, prompt)
+	}
+	env.OpenStorage = func() (TrackerStorage, error) {
+		return openLedger(defaultStorageFile, defaultEncryptedFile, env.Passphrase, env.Backups)
+	}
+	return env
+}
+
+// openLedger returns encrypted storage if the encrypted file exists, csv storage otherwise.
+func openLedger(csvFile, encryptedFile string, passphrase func(prompt string) (string, error), backups *Backups) (TrackerStorage, error) {
+	_, err := os.Stat(encryptedFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		storage := NewStorageFromFile(csvFile)
+		storage.SetBackups(backups)
+		storage.SetLenient(true)
+		return storage, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = os.Stat(csvFile)
+	if err == nil {
+		return nil, fmt.Errorf("both %s and %s exist, remove one of them", csvFile, encryptedFile)
+	}
+
+	secret, err := passphrase("Passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	storage := NewEncryptedStorage(encryptedFile, secret)
+	storage.SetBackups(backups)
+	return storage, nil
+}
+
+// terminalFile returns the file behind a stream if it is a terminal.
+func terminalFile(stream any) (*os.File, bool) {
+	file, ok := stream.(*os.File)
+	return file, ok && isTerminal(file)
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	ExportCsv  = "csv"
+	ExportJson = "json"
+	ExportXlsx = "xlsx"
+)
+
+// exportHeaders include kind and account so income and transfers are not mistaken for expenses,
+// text columns from Description on are filled by exportText.
+var exportHeaders = []string{"Id", "Date", "Amount", "Description", "Kind", "Account", "Payee", "Notes", "Tags"}
+
+// exportText returns text columns of the record in csv and xlsx exports.
+func exportText(record TrackerRecord) []string {
+	return []string{record.Description, record.Kind.String(), accountName(record), record.Payee, record.Notes, formatTags(record.Tags)}
+}
+
+func ExportToCsv(w io.Writer, records []TrackerRecord, delimiter rune, dateFormat string) error {
+	writer := csv.NewWriter(w)
+	writer.Comma = delimiter
+
+	err := writer.Write(exportHeaders)
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		row := []string{
+			strconv.FormatUint(uint64(record.Id), 10),
+			record.CreatedAt.Format(dateFormat),
+			strconv.FormatUint(uint64(record.Amount), 10),
+		}
+		err := writer.Write(append(row, exportText(record)...))
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+type jsonRecord struct {
+	Id          RecordId         `json:"id"`
+	Uid         string           `json:"uid,omitempty"`
+	Date        time.Time        `json:"date"`
+	Amount      uint             `json:"amount"`
+	Description string           `json:"description"`
+	Tags        []string         `json:"tags,omitempty"`
+	Payee       string           `json:"payee,omitempty"`
+	Notes       string           `json:"notes,omitempty"`
+	Attachments []jsonAttachment `json:"attachments,omitempty"`
+	Kind        RecordKind       `json:"kind,omitempty"`
+	Account     string           `json:"account,omitempty"`
+	Transfer    string           `json:"transfer,omitempty"`
+}
+
+type jsonAttachment struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+}
+
+func toJsonRecord(record TrackerRecord) jsonRecord {
+	return jsonRecord{
+		Id:          record.Id,
+		Uid:         record.Uid,
+		Date:        record.CreatedAt,
+		Amount:      record.Amount,
+		Description: record.Description,
+		Tags:        record.Tags,
+		Payee:       record.Payee,
+		Notes:       record.Notes,
+		Attachments: toJsonAttachments(record.Attachments),
+		Kind:        record.Kind,
+		Account:     record.Account,
+		Transfer:    record.Transfer,
+	}
+}
+
+func toJsonAttachments(attachments []Attachment) []jsonAttachment {
+	if len(attachments) == 0 {
+		return nil
+	}
+	items := make([]jsonAttachment, 0, len(attachments))
+	for _, attachment := range attachments {
+		items = append(items, jsonAttachment{Name: attachment.Name, Key: attachment.Key})
+	}
+	return items
+}
+
+func ExportToJson(w io.Writer, records []TrackerRecord) error {
+	items := make([]jsonRecord, 0, len(records))
+	for _, record := range records {
+		items = append(items, toJsonRecord(record))
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(items)
+}
+
+// ExportToXlsx writes a minimal Office Open XML workbook with a single sheet,
+// readable by Excel, LibreOffice and other spreadsheet applications.
+func ExportToXlsx(w io.Writer, records []TrackerRecord) error {
+	archive := zip.NewWriter(w)
+
+	sheet, err := xlsxSheet(records)
+	if err != nil {
+		return err
+	}
+
+	parts := []struct {
+		name    string
+		content []byte
+	}{
+		{"[Content_Types].xml", []byte(xlsxContentTypes)},
+		{"_rels/.rels", []byte(xlsxRootRels)},
+		{"xl/workbook.xml", []byte(xlsxWorkbook)},
+		{"xl/_rels/workbook.xml.rels", []byte(xlsxWorkbookRels)},
+		{"xl/styles.xml", []byte(xlsxStyles)},
+		{"xl/worksheets/sheet1.xml", sheet},
+	}
+	for _, part := range parts {
+		file, err := archive.Create(part.name)
+		if err != nil {
+			return err
+		}
+		_, err = file.Write(part.content)
+		if err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+type xlsxWorksheet struct {
+	XMLName xml.Name  `xml:"worksheet"`
+	Xmlns   string    `xml:"xmlns,attr"`
+	Rows    []xlsxRow `xml:"sheetData>row"`
+}
+
+type xlsxRow struct {
+	Index int        `xml:"r,attr"`
+	Cells []xlsxCell `xml:"c"`
+}
+
+type xlsxCell struct {
+	Ref          string            `xml:"r,attr"`
+	Type         string            `xml:"t,attr,omitempty"`
+	Style        int               `xml:"s,attr,omitempty"`
+	Value        string            `xml:"v,omitempty"`
+	InlineString *xlsxInlineString `xml:"is,omitempty"`
+}
+
+type xlsxInlineString struct {
+	Text string `xml:"t"`
+}
+
+// style index of the date-time cell format declared in xlsxStyles
+const xlsxDateStyle = 1
+
+// spreadsheet serial dates count days from this epoch
+var xlsxEpoch = time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC)
+
+func xlsxSheet(records []TrackerRecord) ([]byte, error) {
+	worksheet := xlsxWorksheet{Xmlns: "http://schemas.openxmlformats.org/spreadsheetml/2006/main"}
+
+	header := xlsxRow{Index: 1}
+	for i, title := range exportHeaders {
+		header.Cells = append(header.Cells, xlsxStringCell(i, 1, title))
+	}
+	worksheet.Rows = append(worksheet.Rows, header)
+
+	for i, record := range records {
+		index := i + 2
+		row := xlsxRow{
+			Index: index,
+			Cells: []xlsxCell{
+				{Ref: xlsxRef(0, index), Value: strconv.FormatUint(uint64(record.Id), 10)},
+				{Ref: xlsxRef(1, index), Style: xlsxDateStyle, Value: xlsxSerialDate(record.CreatedAt)},
+				{Ref: xlsxRef(2, index), Value: strconv.FormatUint(uint64(record.Amount), 10)},
+			},
+		}
+		for j, text := range exportText(record) {
+			if text != "" {
+				row.Cells = append(row.Cells, xlsxStringCell(3+j, index, text))
+			}
+		}
+		worksheet.Rows = append(worksheet.Rows, row)
+	}
+
+	content, err := xml.Marshal(worksheet)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), content...), nil
+}
+
+func xlsxStringCell(column, row int, text string) xlsxCell {
+	return xlsxCell{Ref: xlsxRef(column, row), Type: "inlineStr", InlineString: &xlsxInlineString{Text: text}}
+}
+
+func xlsxRef(column, row int) string {
+	return fmt.Sprintf("%c%d", 'A'+column, row)
+}
+
+func xlsxSerialDate(t time.Time) string {
+	wallClock := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+	days := wallClock.Sub(xlsxEpoch).Hours() / 24
+	return strconv.FormatFloat(days, 'f', -1, 64)
+}
+
+const xlsxContentTypes = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>
+</Types>`
+
+const xlsxRootRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+
+const xlsxWorkbook = xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Expenses" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`
+
+const xlsxWorkbookRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+</Relationships>`
+
+// numFmtId 22 is the built-in "m/d/yy h:mm" format, displayed using the reader's locale
+const xlsxStyles = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<fonts count="1"><font><sz val="11"/><name val="Calibri"/></font></fonts>
+<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>
+<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="22" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/></cellXfs>
+</styleSheet>`
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+var exportTestRecords = []TrackerRecord{
+	{Id: 1, CreatedAt: time.Date(2024, 1, 1, 1, 1, 1, 0, time.UTC), Amount: 100, Description: "record1"},
+	{Id: 2, CreatedAt: time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC), Amount: 200, Description: "long; lorem <ipsum>"},
+}
+
+func TestExportToCsv(t *testing.T) {
+	tests := []struct {
+		name       string
+		records    []TrackerRecord
+		delimiter  rune
+		dateFormat string
+		expected   string
+	}{
+		{
+			name:       "EmptyRecords",
+			delimiter:  ',',
+			dateFormat: time.DateOnly,
+			expected:   "Id,Date,Amount,Description,Kind,Account,Payee,Notes,Tags\n",
+		},
+		{
+			name:       "DefaultOptions",
+			records:    exportTestRecords,
+			delimiter:  ',',
+			dateFormat: time.DateOnly,
+			expected:   "Id,Date,Amount,Description,Kind,Account,Payee,Notes,Tags\n1,2024-01-01,100,record1,expense,default,,,\n2,2024-01-02,200,long; lorem <ipsum>,expense,default,,,\n",
+		},
+		{
+			name: "IncomeAndTransfers",
+			records: []TrackerRecord{
+				{Id: 1, CreatedAt: time.Date(2024, 1, 1, 1, 1, 1, 0, time.UTC), Amount: 1000, Description: "Salary", Kind: KindIncome, Account: "checking"},
+				{Id: 2, CreatedAt: time.Date(2024, 1, 2, 1, 1, 1, 0, time.UTC), Amount: 100, Description: "Withdrawal", Kind: KindTransferOut, Account: "checking"},
+				{Id: 3, CreatedAt: time.Date(2024, 1, 2, 1, 1, 1, 0, time.UTC), Amount: 100, Description: "Withdrawal", Kind: KindTransferIn, Account: "cash"},
+			},
+			delimiter:  ',',
+			dateFormat: time.DateOnly,
+			expected: "Id,Date,Amount,Description,Kind,Account,Payee,Notes,Tags\n" +
+				"1,2024-01-01,1000,Salary,income,checking,,,\n" +
+				"2,2024-01-02,100,Withdrawal,transfer-out,checking,,,\n" +
+				"3,2024-01-02,100,Withdrawal,transfer-in,cash,,,\n",
+		},
+		{
+			name: "PayeeNotesAndTags",
+			records: []TrackerRecord{
+				{Id: 1, CreatedAt: time.Date(2024, 1, 1, 1, 1, 1, 0, time.UTC), Amount: 120, Description: "Hotel", Payee: "Hotel Lisboa", Notes: "two nights, breakfast", Tags: []string{"business", "trip-lisbon"}},
+			},
+			delimiter:  ',',
+			dateFormat: time.DateOnly,
+			expected: "Id,Date,Amount,Description,Kind,Account,Payee,Notes,Tags\n" +
+				"1,2024-01-01,120,Hotel,expense,default,Hotel Lisboa,\"two nights, breakfast\",#business #trip-lisbon\n",
+		},
+		{
+			name:       "CustomDelimiterAndDateFormat",
+			records:    exportTestRecords,
+			delimiter:  ';',
+			dateFormat: "02.01.2006 15:04",
+			expected:   "Id;Date;Amount;Description;Kind;Account;Payee;Notes;Tags\n1;01.01.2024 01:01;100;record1;expense;default;;;\n2;02.01.2024 12:00;200;\"long; lorem <ipsum>\";expense;default;;;\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := ExportToCsv(&buf, tt.records, tt.delimiter, tt.dateFormat); err != nil {
+				t.Fatalf("ExportToCsv() error = %v", err)
+			}
+			if got := buf.String(); got != tt.expected {
+				t.Errorf("ExportToCsv() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExportToJson(t *testing.T) {
+	var buf bytes.Buffer
+	if err := ExportToJson(&buf, exportTestRecords[:1]); err != nil {
+		t.Fatalf("ExportToJson() error = %v", err)
+	}
+	expected := `[
+  {
+    "id": 1,
+    "date": "2024-01-01T01:01:01Z",
+    "amount": 100,
+    "description": "record1"
+  }
+]
+`
+	if got := buf.String(); got != expected {
+		t.Errorf("ExportToJson() = %s, want %s", got, expected)
+	}
+
+	buf.Reset()
+	if err := ExportToJson(&buf, nil); err != nil {
+		t.Fatalf("ExportToJson() error = %v", err)
+	}
+	if got := buf.String(); got != "[]\n" {
+		t.Errorf("ExportToJson() with no records = %q, want %q", got, "[]\n")
+	}
+}
+
+func TestExportToXlsx(t *testing.T) {
+	var buf bytes.Buffer
+	if err := ExportToXlsx(&buf, exportTestRecords); err != nil {
+		t.Fatalf("ExportToXlsx() error = %v", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("ExportToXlsx() produced invalid zip: %v", err)
+	}
+
+	files := make(map[string]string)
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", file.Name, err)
+		}
+		content, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", file.Name, err)
+		}
+		files[file.Name] = string(content)
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml", "xl/worksheets/sheet1.xml"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("ExportToXlsx() missing part %s", name)
+		}
+	}
+
+	sheet := files["xl/worksheets/sheet1.xml"]
+	expectedCells := []string{
+		`<c r="A1" t="inlineStr"><is><t>Id</t></is></c>`,
+		`<c r="A2"><v>1</v></c>`,
+		`<c r="B2" s="1"><v>45292.04237268519</v></c>`,
+		`<c r="C3"><v>200</v></c>`,
+		`<c r="D3" t="inlineStr"><is><t>long; lorem &lt;ipsum&gt;</t></is></c>`,
+		`<c r="E1" t="inlineStr"><is><t>Kind</t></is></c>`,
+		`<c r="E2" t="inlineStr"><is><t>expense</t></is></c>`,
+		`<c r="F2" t="inlineStr"><is><t>default</t></is></c>`,
+		`<c r="G1" t="inlineStr"><is><t>Payee</t></is></c>`,
+		`<c r="I1" t="inlineStr"><is><t>Tags</t></is></c>`,
+	}
+	for _, cell := range expectedCells {
+		if !strings.Contains(sheet, cell) {
+			t.Errorf("ExportToXlsx() sheet does not contain %s, got %s", cell, sheet)
+		}
+	}
+
+	tagged, err := xlsxSheet([]TrackerRecord{{Id: 1, CreatedAt: time.Date(2024, 1, 1, 1, 1, 1, 0, time.UTC), Amount: 120, Description: "Hotel", Payee: "Hotel Lisboa", Notes: "two nights", Tags: []string{"business"}}})
+	if err != nil {
+		t.Fatalf("xlsxSheet() error = %v", err)
+	}
+	for _, cell := range []string{
+		`<c r="G2" t="inlineStr"><is><t>Hotel Lisboa</t></is></c>`,
+		`<c r="H2" t="inlineStr"><is><t>two nights</t></is></c>`,
+		`<c r="I2" t="inlineStr"><is><t>#business</t></is></c>`,
+	} {
+		if !strings.Contains(string(tagged), cell) {
+			t.Errorf("xlsxSheet() does not contain %s, got %s", cell, tagged)
+		}
+	}
+}
+
+func TestExportFormatOf(t *testing.T) {
+	tests := []struct {
+		output string
+		want   string
+	}{
+		{output: "report.json", want: ExportJson},
+		{output: "report.XLSX", want: ExportXlsx},
+		{output: "report.CSV", want: ExportCsv},
+		{output: "report.txt", want: ExportCsv},
+		{output: "report", want: ExportCsv},
+		{output: "-", want: ExportCsv},
+	}
+	for _, tt := range tests {
+		t.Run(tt.output, func(t *testing.T) {
+			if got := exportFormatOf(tt.output); got != tt.want {
+				t.Errorf("exportFormatOf(%q) = %q, want %q", tt.output, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,12 +1,31 @@
 package main
 
-const HelpText = `Usage: expense-tracker <command> [options]
+import (
+	"strings"
+)
 
-expense-tracker add --description <description> --amount <amount>
-expense-tracker update --id <id> [--description <description>] [--amount <amount>]
-expense-tracker delete --id <id>
-expense-tracker list
-expense-tracker summary [--month <number>] [--year <number>]
-
-add --help to any command to get detailed information
+const periodHelp = `<period> is one of:
+  --year <number>
+  --month <number> [--year <number>]
+  --week [<number>] [--year <number>]
+  --quarter Q<number> [--year <number>]
+  [--from <YYYY-MM-DD>] [--to <YYYY-MM-DD>]
+  --last <number>d|w|m|y
 `
+
+// HelpText returns usage of all visible commands generated from the registry.
+func HelpText() string {
+	var text strings.Builder
+	text.WriteString("Usage: expense-tracker <command> [options]\n\n")
+	for _, command := range visibleCommands(Commands) {
+		if command.Name == "help" {
+			continue
+		}
+		text.WriteString(command.usageLine())
+		text.WriteString("\n")
+	}
+	text.WriteString("\n")
+	text.WriteString(periodHelp)
+	text.WriteString("\nrun expense-tracker help <command> or add --help to any command to get detailed information\n")
+	return text.String()
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"unicode/utf8"
+)
+
+type keyCode int
+
+const (
+	keyRune keyCode = iota
+	keyCtrl
+	keyEnter
+	keyTab
+	keyBackspace
+	keyDelete
+	keyEscape
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+	keyHome
+	keyEnd
+	keyPageUp
+	keyPageDown
+	keyUnknown
+)
+
+// keyEvent is a key press read from a terminal in raw mode,
+// r holds the typed character for keyRune and the lowercase letter for keyCtrl.
+type keyEvent struct {
+	code keyCode
+	r    rune
+}
+
+func readKey(in *bufio.Reader) (keyEvent, error) {
+	b, err := in.ReadByte()
+	if err != nil {
+		return keyEvent{}, err
+	}
+
+	switch {
+	case b == '\r' || b == '\n':
+		return keyEvent{code: keyEnter}, nil
+	case b == '\t':
+		return keyEvent{code: keyTab}, nil
+	case b == 0x7f || b == 0x08:
+		return keyEvent{code: keyBackspace}, nil
+	case b == 0x1b:
+		return readEscapeSequence(in)
+	case b < 0x20:
+		return keyEvent{code: keyCtrl, r: rune('a' + b - 1)}, nil
+	case b < utf8.RuneSelf:
+		return keyEvent{code: keyRune, r: rune(b)}, nil
+	}
+
+	// multibyte character, read the rest of its bytes
+	err = in.UnreadByte()
+	if err != nil {
+		return keyEvent{}, err
+	}
+	r, _, err := in.ReadRune()
+	if err != nil {
+		return keyEvent{}, err
+	}
+	return keyEvent{code: keyRune, r: r}, nil
+}
+
+// readEscapeSequence decodes CSI and SS3 sequences sent by arrows and navigation keys,
+// escape not followed by already received bytes is the escape key itself.
+func readEscapeSequence(in *bufio.Reader) (keyEvent, error) {
+	if in.Buffered() == 0 {
+		return keyEvent{code: keyEscape}, nil
+	}
+	introducer, err := in.ReadByte()
+	if err != nil {
+		return keyEvent{}, err
+	}
+	if introducer != '[' && introducer != 'O' {
+		return keyEvent{code: keyUnknown}, nil
+	}
+
+	// parameters are digits and semicolons, sequence ends with a letter or tilde
+	var params []byte
+	for {
+		b, err := in.ReadByte()
+		if err != nil {
+			return keyEvent{}, err
+		}
+		if (b >= '0' && b <= '9') || b == ';' {
+			params = append(params, b)
+			continue
+		}
+
+		switch b {
+		case 'A':
+			return keyEvent{code: keyUp}, nil
+		case 'B':
+			return keyEvent{code: keyDown}, nil
+		case 'C':
+			return keyEvent{code: keyRight}, nil
+		case 'D':
+			return keyEvent{code: keyLeft}, nil
+		case 'H':
+			return keyEvent{code: keyHome}, nil
+		case 'F':
+			return keyEvent{code: keyEnd}, nil
+		case '~':
+			switch string(params) {
+			case "1", "7":
+				return keyEvent{code: keyHome}, nil
+			case "4", "8":
+				return keyEvent{code: keyEnd}, nil
+			case "3":
+				return keyEvent{code: keyDelete}, nil
+			case "5":
+				return keyEvent{code: keyPageUp}, nil
+			case "6":
+				return keyEvent{code: keyPageDown}, nil
+			}
+		}
+		return keyEvent{code: keyUnknown}, nil
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+var invalidKind = errors.New("invalid kind, expected expense, income, transfer-in or transfer-out")
+
+// RecordKind tells whether a record is money spent, received or moved between accounts.
+type RecordKind string
+
+const (
+	// records of ledgers written before kinds existed have no kind and are expenses
+	KindExpense RecordKind = ""
+	KindIncome  RecordKind = "income"
+	// halves of a transfer between accounts, they are neither spent nor received
+	KindTransferIn  RecordKind = "transfer-in"
+	KindTransferOut RecordKind = "transfer-out"
+)
+
+func (k RecordKind) String() string {
+	if k == KindExpense {
+		return "expense"
+	}
+	return string(k)
+}
+
+func parseRecordKind(value string) (RecordKind, error) {
+	switch value {
+	case "", "expense":
+		return KindExpense, nil
+	case "income", "transfer-in", "transfer-out":
+		return RecordKind(value), nil
+	}
+	return "", fmt.Errorf("%w: %q", invalidKind, value)
+}
+
+// Balance sums income and expenses of records, transfers are not counted.
+type Balance struct {
+	Income   uint
+	Expenses uint
+}
+
+// Net is income minus expenses, negative when more was spent than received.
+func (b Balance) Net() int64 {
+	return int64(b.Income) - int64(b.Expenses)
+}
+
+func ComputeBalance(records []TrackerRecord) Balance {
+	var balance Balance
+	for _, record := range records {
+		switch record.Kind {
+		case KindIncome:
+			balance.Income += record.Amount
+		case KindExpense:
+			balance.Expenses += record.Amount
+		}
+	}
+	return balance
+}
+
+// formatSignedAmount returns the amount of money coming in, income and transfers in, with a leading +.
+func formatSignedAmount(record TrackerRecord) string {
+	if record.Kind == KindIncome || record.Kind == KindTransferIn {
+		return fmt.Sprintf("+%d", record.Amount)
+	}
+	return fmt.Sprintf("%d", record.Amount)
+}
+
+// expensesOnly returns records without income and transfers, spending reports are built from expenses.
+func expensesOnly(records []TrackerRecord) []TrackerRecord {
+	expenses := make([]TrackerRecord, 0, len(records))
+	for _, record := range records {
+		if record.Kind == KindExpense {
+			expenses = append(expenses, record)
+		}
+	}
+	return expenses
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseRecordKind(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    RecordKind
+		wantErr error
+	}{
+		{value: "", want: KindExpense},
+		{value: "expense", want: KindExpense},
+		{value: "income", want: KindIncome},
+		{value: "transfer-in", want: KindTransferIn},
+		{value: "transfer-out", want: KindTransferOut},
+		{value: "Income", wantErr: invalidKind},
+		{value: "transfer", wantErr: invalidKind},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseRecordKind(tt.value)
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("parseRecordKind(%q) = %q, %v, want %q, %v", tt.value, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestComputeBalance(t *testing.T) {
+	tests := []struct {
+		name    string
+		records []TrackerRecord
+		want    Balance
+		wantNet int64
+	}{
+		{name: "Empty"},
+		{name: "ExpensesOnly", records: []TrackerRecord{{Amount: 10}, {Amount: 5}}, want: Balance{Expenses: 15}, wantNet: -15},
+		{
+			name:    "IncomeExpensesAndTransfers",
+			records: []TrackerRecord{{Amount: 10}, {Amount: 100, Kind: KindIncome}, {Amount: 30}, {Amount: 50, Kind: KindTransferOut}, {Amount: 50, Kind: KindTransferIn}},
+			want:    Balance{Income: 100, Expenses: 40},
+			wantNet: 60,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ComputeBalance(tt.records)
+			if got != tt.want || got.Net() != tt.wantNet {
+				t.Errorf("ComputeBalance() = %v with net %d, want %v with net %d", got, got.Net(), tt.want, tt.wantNet)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+var errInterrupted = errors.New("interrupted")
+
+// lineEditor reads lines from a terminal in raw mode with cursor movement,
+// history navigation by up and down keys and completion by tab key.
+type lineEditor struct {
+	in      *bufio.Reader
+	out     io.Writer
+	history []string
+	// complete returns candidates to replace the last word of the text before cursor
+	complete func(before string) []string
+
+	prompt  string
+	line    []rune
+	cursor  int
+	draft   []rune
+	histPos int
+}
+
+func newLineEditor(in io.Reader, out io.Writer, complete func(before string) []string) *lineEditor {
+	return &lineEditor{in: bufio.NewReader(in), out: out, complete: complete}
+}
+
+// ReadLine reads a line in raw mode, returns errInterrupted on Ctrl+C and io.EOF on Ctrl+D on an empty line.
+func (e *lineEditor) ReadLine(prompt string) (string, error) {
+	e.prompt = prompt
+	e.line = e.line[:0]
+	e.cursor = 0
+	e.histPos = len(e.history)
+	e.redraw()
+
+	for {
+		key, err := readKey(e.in)
+		if err != nil {
+			return "", err
+		}
+
+		switch key.code {
+		case keyEnter:
+			fmt.Fprint(e.out, "\r\n")
+			line := string(e.line)
+			e.addHistory(line)
+			return line, nil
+		case keyRune:
+			e.insert([]rune{key.r})
+		case keyBackspace:
+			if e.cursor > 0 {
+				e.line = append(e.line[:e.cursor-1], e.line[e.cursor:]...)
+				e.cursor--
+			}
+		case keyDelete:
+			if e.cursor < len(e.line) {
+				e.line = append(e.line[:e.cursor], e.line[e.cursor+1:]...)
+			}
+		case keyLeft:
+			e.cursor = max(e.cursor-1, 0)
+		case keyRight:
+			e.cursor = min(e.cursor+1, len(e.line))
+		case keyHome:
+			e.cursor = 0
+		case keyEnd:
+			e.cursor = len(e.line)
+		case keyUp:
+			e.historyPrev()
+		case keyDown:
+			e.historyNext()
+		case keyTab:
+			e.completeWord()
+		case keyCtrl:
+			switch key.r {
+			case 'a':
+				e.cursor = 0
+			case 'e':
+				e.cursor = len(e.line)
+			case 'u':
+				e.line = append(e.line[:0], e.line[e.cursor:]...)
+				e.cursor = 0
+			case 'c':
+				fmt.Fprint(e.out, "^C\r\n")
+				return "", errInterrupted
+			case 'd':
+				if len(e.line) == 0 {
+					fmt.Fprint(e.out, "\r\n")
+					return "", io.EOF
+				}
+			}
+		}
+		e.redraw()
+	}
+}
+
+// ReadPlainLine reads a line without editing, used when input is not a terminal.
+func (e *lineEditor) ReadPlainLine() (string, error) {
+	line, err := e.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	e.addHistory(line)
+	return line, nil
+}
+
+func (e *lineEditor) History() []string {
+	return e.history
+}
+
+func (e *lineEditor) addHistory(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	if len(e.history) > 0 && e.history[len(e.history)-1] == line {
+		return
+	}
+	e.history = append(e.history, line)
+}
+
+func (e *lineEditor) insert(runes []rune) {
+	line := make([]rune, 0, len(e.line)+len(runes))
+	line = append(line, e.line[:e.cursor]...)
+	line = append(line, runes...)
+	e.line = append(line, e.line[e.cursor:]...)
+	e.cursor += len(runes)
+}
+
+func (e *lineEditor) historyPrev() {
+	if e.histPos == 0 {
+		return
+	}
+	if e.histPos == len(e.history) {
+		e.draft = append(e.draft[:0], e.line...)
+	}
+	e.histPos--
+	e.setLine([]rune(e.history[e.histPos]))
+}
+
+func (e *lineEditor) historyNext() {
+	if e.histPos == len(e.history) {
+		return
+	}
+	e.histPos++
+	if e.histPos == len(e.history) {
+		e.setLine(e.draft)
+	} else {
+		e.setLine([]rune(e.history[e.histPos]))
+	}
+}
+
+func (e *lineEditor) setLine(line []rune) {
+	e.line = append(e.line[:0], line...)
+	e.cursor = len(e.line)
+}
+
+// completeWord extends the word before cursor to the longest common prefix of candidates,
+// a single candidate is completed with a trailing space, ambiguous ones are listed below the line.
+func (e *lineEditor) completeWord() {
+	if e.complete == nil {
+		return
+	}
+	before := string(e.line[:e.cursor])
+	candidates := e.complete(before)
+	if len(candidates) == 0 {
+		return
+	}
+
+	wordStart := strings.LastIndexFunc(before, unicode.IsSpace) + 1
+	word := before[wordStart:]
+
+	prefix := candidates[0]
+	for _, candidate := range candidates[1:] {
+		prefix = commonPrefix(prefix, candidate)
+	}
+	if len(candidates) == 1 {
+		prefix += " "
+	}
+
+	if len(prefix) > len(word) {
+		e.insert([]rune(prefix[len(word):]))
+		return
+	}
+	if len(candidates) > 1 {
+		fmt.Fprintf(e.out, "\r\n%s\r\n", strings.Join(candidates, "  "))
+	}
+}
+
+func commonPrefix(a, b string) string {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return a[:i]
+}
+
+// redraw rewrites the current terminal line and places the cursor.
+func (e *lineEditor) redraw() {
+	fmt.Fprintf(e.out, "\r%s%s\x1b[K", e.prompt, string(e.line))
+	if back := len(e.line) - e.cursor; back > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dD", back)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadKey(t *testing.T) {
+	input := "a\r\t\x7f\x03\x1b[A\x1b[B\x1b[C\x1b[D\x1bOH\x1b[4~\x1b[3~\x1b[6~é\x1b"
+	expected := []keyEvent{
+		{code: keyRune, r: 'a'},
+		{code: keyEnter},
+		{code: keyTab},
+		{code: keyBackspace},
+		{code: keyCtrl, r: 'c'},
+		{code: keyUp},
+		{code: keyDown},
+		{code: keyRight},
+		{code: keyLeft},
+		{code: keyHome},
+		{code: keyEnd},
+		{code: keyDelete},
+		{code: keyPageDown},
+		{code: keyRune, r: 'é'},
+		{code: keyEscape},
+	}
+
+	reader := bufio.NewReader(strings.NewReader(input))
+	var got []keyEvent
+	for {
+		key, err := readKey(reader)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("readKey() error = %v", err)
+		}
+		got = append(got, key)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("readKey() = %v, want %v", got, expected)
+	}
+}
+
+func TestLineEditorReadLine(t *testing.T) {
+	complete := func(before string) []string {
+		var candidates []string
+		for _, option := range []string{"summary", "stats", "--month"} {
+			word := before[strings.LastIndex(before, " ")+1:]
+			if strings.HasPrefix(option, word) {
+				candidates = append(candidates, option)
+			}
+		}
+		return candidates
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr error
+	}{
+		{name: "Plain", input: "list\r", want: []string{"list"}},
+		{name: "Backspace", input: "lisx\x7ft\r", want: []string{"list"}},
+		{name: "CursorMovement", input: "ist\x1b[Dt\x1b[Hl\x1b[3~\x05s\r", want: []string{"lstts"}},
+		{name: "ClearLine", input: "garbage\x15list\r", want: []string{"list"}},
+		{name: "HistoryUp", input: "list\rsummary\r\x1b[A\x1b[A\r", want: []string{"list", "summary", "list"}},
+		{name: "HistoryDownRestoresDraft", input: "list\rdra\x1b[A\x1b[Bft\r", want: []string{"list", "draft"}},
+		{name: "CompleteSingle", input: "su\t--m\t3\r", want: []string{"summary --month 3"}},
+		{name: "CompleteAmbiguous", input: "s\t\r", want: []string{"s"}},
+		{name: "Interrupt", input: "list\x03", wantErr: errInterrupted},
+		{name: "EndOfInputOnEmptyLine", input: "\x04", wantErr: io.EOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			editor := newLineEditor(strings.NewReader(tt.input), &out, complete)
+
+			var lines []string
+			var err error
+			for {
+				var line string
+				line, err = editor.ReadLine("> ")
+				if err != nil {
+					break
+				}
+				lines = append(lines, line)
+			}
+
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("ReadLine() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && err != io.EOF {
+				t.Errorf("ReadLine() error = %v, want end of input", err)
+			}
+			if !reflect.DeepEqual(lines, tt.want) {
+				t.Errorf("ReadLine() = %q, want %q", lines, tt.want)
+			}
+		})
+	}
+}
+
+func TestLineEditorHistory(t *testing.T) {
+	editor := newLineEditor(strings.NewReader("list\n\nlist\nsummary\r\n"), io.Discard, nil)
+	for {
+		if _, err := editor.ReadPlainLine(); err != nil {
+			break
+		}
+	}
+	if want := []string{"list", "summary"}; !reflect.DeepEqual(editor.History(), want) {
+		t.Errorf("History() = %v, want %v", editor.History(), want)
+	}
+}
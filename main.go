@@ -1,43 +1,111 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"time"
 )
 
+// timezoneVariable names the environment variable with IANA name of the reporting timezone.
+const timezoneVariable = "EXPENSE_TRACKER_TZ"
+
 func main() {
-	err := Run(os.Args[1:])
+	env := DefaultEnv()
+	location, err := LoadLocation(os.Getenv(timezoneVariable))
 	if err != nil {
-		os.Exit(2)
+		fmt.Fprintf(os.Stderr, "invalid %s: %v\n", timezoneVariable, err)
+		os.Exit(ExitCode(err))
+	}
+	env.Location = location
+
+	err = Run(os.Args[1:], env)
+	os.Exit(ExitCode(err))
+}
+
+// LoadLocation returns the timezone with IANA name, e.g. Europe/Berlin, time.Local for empty name.
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(name)
+}
+
+// ExitCode returns the process exit code for the error returned by Run.
+func ExitCode(err error) int {
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		return 0
 	}
+	return 2
 }
 
-func Run(args []string) error {
+func Run(args []string, env *Env) error {
 	if len(args) == 0 {
-		return HelpCmd()
+		fmt.Fprint(env.Stdout, HelpText())
+		return nil
 	}
 
-	storage := NewStorageFromFile("./expenses.csv")
-	tracker, err := NewTracker(storage)
+	if args[0] == "-h" || args[0] == "--help" {
+		fmt.Fprint(env.Stdout, HelpText())
+		return nil
+	}
+	command, err := lookupCommand(args[0])
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error creating tracker: %v\n", err)
+		printUnknownCommand(env.Stderr, err)
 		return err
 	}
+	if command.NoTracker {
+		return command.Run(args[1:], env, nil)
+	}
+
+	tracker, err := openTracker(env)
+	if err != nil {
+		return err
+	}
+	return command.Run(args[1:], env, tracker)
+}
 
-	switch args[0] {
-	case "help", "-h", "--help":
-		return HelpCmd()
-	case "add":
-		return AddCmd(args[1:], tracker)
-	case "update":
-		return UpdateCmd(args[1:], tracker)
-	case "delete":
-		return DeleteCmd(args[1:], tracker)
-	case "list":
-		return ListCmd(args[1:], tracker)
-	case "summary":
-		return SummaryCmd(args[1:], tracker)
-	default:
-		return HelpCmd()
+func openTracker(env *Env) (*Tracker, error) {
+	storage, err := env.OpenStorage()
+	if err != nil {
+		fmt.Fprintf(env.Stderr, "Error opening storage: %v\n", err)
+		return nil, err
+	}
+	tracker, err := NewTrackerWithClock(storage, env.Now)
+	if err != nil {
+		fmt.Fprintf(env.Stderr, "Error creating tracker: %v\n", err)
+		return nil, err
+	}
+	tracker.SetLocation(env.Location)
+	if env.Attachments != nil {
+		tracker.SetAttachments(env.Attachments)
+	}
+	printLedgerWarnings(env.Stderr, tracker.Warnings())
+	return tracker, nil
+}
+
+// printLedgerWarnings reports rows skipped on load, the ledger stays read-only until it is repaired.
+func printLedgerWarnings(w io.Writer, warnings []LedgerIssue) {
+	if len(warnings) == 0 {
+		return
+	}
+	for _, warning := range warnings {
+		fmt.Fprintf(w, "warning: line %d skipped: %s\n", warning.Line, warning.Reason)
+	}
+	fmt.Fprintln(w, "warning: ledger is read-only, run expense-tracker doctor --repair to fix it")
+}
+
+func runCommand(args []string, env *Env, tracker *Tracker) error {
+	if args[0] == "-h" || args[0] == "--help" {
+		fmt.Fprint(env.Stdout, HelpText())
+		return nil
+	}
+	command, err := lookupCommand(args[0])
+	if err != nil {
+		printUnknownCommand(env.Stderr, err)
+		return err
 	}
+	return command.Run(args[1:], env, tracker)
 }
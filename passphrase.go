@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// passphraseVariable names the environment variable with the passphrase of the encrypted ledger.
+const passphraseVariable = "EXPENSE_TRACKER_PASSPHRASE"
+
+var passphraseRequired = errors.New("passphrase required, enter it in a terminal or set " + passphraseVariable)
+
+// environmentPassphrase returns the passphrase from the environment variable and never prompts.
+func environmentPassphrase(_ string) (string, error) {
+	passphrase, ok := os.LookupEnv(passphraseVariable)
+	if !ok {
+		return "", passphraseRequired
+	}
+	return passphrase, nil
+}
+
+// readPassphrase returns the passphrase from the environment variable,
+// otherwise prompts for it in the terminal without echo.
+func readPassphrase(stdin io.Reader, stderr io.Writer, prompt string) (string, error) {
+	passphrase, ok := os.LookupEnv(passphraseVariable)
+	if ok {
+		return passphrase, nil
+	}
+
+	terminal, ok := terminalFile(stdin)
+	if !ok {
+		return "", passphraseRequired
+	}
+	restore, err := disableEcho(terminal)
+	if err != nil {
+		// not a real terminal, e.g. /dev/null
+		return "", passphraseRequired
+	}
+	defer restore()
+
+	fmt.Fprint(stderr, prompt)
+	defer fmt.Fprintln(stderr)
+
+	// read byte by byte to leave the rest of input to the command
+	var line strings.Builder
+	buffer := make([]byte, 1)
+	for {
+		n, err := terminal.Read(buffer)
+		if n == 1 && buffer[0] != '\n' {
+			line.WriteByte(buffer[0])
+			continue
+		}
+		if n == 1 || errors.Is(err, io.EOF) {
+			return strings.TrimSuffix(line.String(), "\r"), nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+type PayeeTotal struct {
+	Payee string
+	Count int
+	Total uint
+}
+
+// ComputePayeeTotals counts records and sums amounts per payee ignoring case, sorted by total, largest first.
+// Records without payee are skipped, each payee is shown as spelled in its first record.
+func ComputePayeeTotals(records []TrackerRecord) []PayeeTotal {
+	totals := make(map[string]PayeeTotal)
+	for _, record := range records {
+		if record.Payee == "" {
+			continue
+		}
+		key := strings.ToLower(record.Payee)
+		total, ok := totals[key]
+		if !ok {
+			total.Payee = record.Payee
+		}
+		total.Count++
+		total.Total += record.Amount
+		totals[key] = total
+	}
+
+	result := make([]PayeeTotal, 0, len(totals))
+	for _, total := range totals {
+		result = append(result, total)
+	}
+	slices.SortFunc(result, func(a, b PayeeTotal) int {
+		if a.Total != b.Total {
+			return cmp.Compare(b.Total, a.Total)
+		}
+		return strings.Compare(strings.ToLower(a.Payee), strings.ToLower(b.Payee))
+	})
+	return result
+}
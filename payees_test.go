@@ -0,0 +1,40 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestComputePayeeTotals(t *testing.T) {
+	tests := []struct {
+		name    string
+		records []TrackerRecord
+		want    []PayeeTotal
+	}{
+		{name: "Empty", want: []PayeeTotal{}},
+		{name: "WithoutPayees", records: []TrackerRecord{{Amount: 10}}, want: []PayeeTotal{}},
+		{
+			name: "LargestFirstIgnoringCase",
+			records: []TrackerRecord{
+				{Amount: 10, Payee: "Cafe Central"},
+				{Amount: 50, Payee: "Grocer"},
+				{Amount: 15, Payee: "cafe central"},
+				{Amount: 25, Payee: "Bakery"},
+				{Amount: 5},
+			},
+			want: []PayeeTotal{
+				{Payee: "Grocer", Count: 1, Total: 50},
+				{Payee: "Bakery", Count: 1, Total: 25},
+				{Payee: "Cafe Central", Count: 2, Total: 25},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ComputePayeeTotals(tt.records); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ComputePayeeTotals() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	invalidDate    = errors.New("invalid date, expected YYYY-MM-DD")
+	invalidPeriod  = errors.New("invalid period, start must be before end")
+	invalidQuarter = errors.New("invalid quarter, expected Q1-Q4")
+	invalidWeek    = errors.New("invalid ISO week number")
+	invalidLength  = errors.New("invalid period length, expected number with d, w, m or y suffix")
+
+	invalidGranularity = errors.New("invalid granularity, expected day, week or month")
+)
+
+// Period is a half-open time range [From, To).
+// A zero From or To leaves that side of the range unbounded.
+type Period struct {
+	From time.Time
+	To   time.Time
+}
+
+func (p Period) Contains(t time.Time) bool {
+	if !p.From.IsZero() && t.Before(p.From) {
+		return false
+	}
+	if !p.To.IsZero() && !t.Before(p.To) {
+		return false
+	}
+	return true
+}
+
+// DateRangePeriod builds a period from inclusive YYYY-MM-DD dates, empty strings leave the side unbounded.
+func DateRangePeriod(from, to string, loc *time.Location) (Period, error) {
+	var period Period
+	if from != "" {
+		date, err := time.ParseInLocation(time.DateOnly, from, loc)
+		if err != nil {
+			return Period{}, errors.Join(invalidDate, err)
+		}
+		period.From = date
+	}
+	if to != "" {
+		date, err := time.ParseInLocation(time.DateOnly, to, loc)
+		if err != nil {
+			return Period{}, errors.Join(invalidDate, err)
+		}
+		period.To = date.AddDate(0, 0, 1)
+	}
+	if !period.From.IsZero() && !period.To.IsZero() && !period.From.Before(period.To) {
+		return Period{}, invalidPeriod
+	}
+	return period, nil
+}
+
+func YearPeriod(year int, loc *time.Location) Period {
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+	return Period{From: from, To: from.AddDate(1, 0, 0)}
+}
+
+func MonthPeriod(month time.Month, year int, loc *time.Location) Period {
+	from := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	return Period{From: from, To: from.AddDate(0, 1, 0)}
+}
+
+func QuarterPeriod(quarter int, year int, loc *time.Location) (Period, error) {
+	if quarter < 1 || quarter > 4 {
+		return Period{}, invalidQuarter
+	}
+	from := time.Date(year, time.Month(3*(quarter-1)+1), 1, 0, 0, 0, 0, loc)
+	return Period{From: from, To: from.AddDate(0, 3, 0)}, nil
+}
+
+// IsoWeekPeriod returns the ISO 8601 week of the ISO week-numbering year:
+// weeks start on Monday and week 1 is the week containing January 4th,
+// so a week may begin in the previous calendar year or end in the next one.
+func IsoWeekPeriod(week int, year int, loc *time.Location) (Period, error) {
+	if week < 1 || week > IsoWeeksInYear(year) {
+		return Period{}, invalidWeek
+	}
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, loc)
+	daysFromMonday := (int(jan4.Weekday()) + 6) % 7
+	from := jan4.AddDate(0, 0, -daysFromMonday+7*(week-1))
+	return Period{From: from, To: from.AddDate(0, 0, 7)}, nil
+}
+
+// IsoWeeksInYear returns 52 or 53, December 28th always falls into the last ISO week of its year.
+func IsoWeeksInYear(year int) int {
+	_, week := time.Date(year, time.December, 28, 0, 0, 0, 0, time.UTC).ISOWeek()
+	return week
+}
+
+// LastPeriod returns a period of the given length ending with the day of now,
+// length is a number followed by unit: d (days), w (weeks), m (months) or y (years), e.g. 30d.
+func LastPeriod(length string, now time.Time) (Period, error) {
+	if len(length) < 2 {
+		return Period{}, invalidLength
+	}
+	count, err := strconv.Atoi(length[:len(length)-1])
+	if err != nil || count < 1 {
+		return Period{}, invalidLength
+	}
+
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, 1)
+	var from time.Time
+	switch length[len(length)-1] {
+	case 'd':
+		from = to.AddDate(0, 0, -count)
+	case 'w':
+		from = to.AddDate(0, 0, -7*count)
+	case 'm':
+		from = to.AddDate(0, -count, 0)
+	case 'y':
+		from = to.AddDate(-count, 0, 0)
+	default:
+		return Period{}, invalidLength
+	}
+	return Period{From: from, To: to}, nil
+}
+
+// ParseQuarter accepts quarter number with optional Q prefix: Q3, q3 or 3.
+func ParseQuarter(value string) (int, error) {
+	value = strings.TrimPrefix(strings.ToUpper(value), "Q")
+	quarter, err := strconv.Atoi(value)
+	if err != nil || quarter < 1 || quarter > 4 {
+		return 0, invalidQuarter
+	}
+	return quarter, nil
+}
+
+type Granularity string
+
+const (
+	ByDay   Granularity = "day"
+	ByWeek  Granularity = "week"
+	ByMonth Granularity = "month"
+)
+
+func ParseGranularity(value string) (Granularity, error) {
+	switch Granularity(value) {
+	case ByDay, ByWeek, ByMonth:
+		return Granularity(value), nil
+	default:
+		return "", invalidGranularity
+	}
+}
+
+// Split divides a bounded period into consecutive calendar days, ISO weeks or months,
+// the first and the last parts are clipped to the period bounds.
+func (p Period) Split(by Granularity) []Period {
+	parts := make([]Period, 0)
+	for from := p.From; from.Before(p.To); {
+		to := nextBoundary(from, by)
+		if to.After(p.To) {
+			to = p.To
+		}
+		parts = append(parts, Period{From: from, To: to})
+		from = to
+	}
+	return parts
+}
+
+func nextBoundary(t time.Time, by Granularity) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch by {
+	case ByWeek:
+		daysFromMonday := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, 7-daysFromMonday)
+	case ByMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location()).AddDate(0, 1, 0)
+	default:
+		return day.AddDate(0, 0, 1)
+	}
+}
+
+// Label returns a short name of the period starting day, ISO week or month.
+func (p Period) Label(by Granularity) string {
+	switch by {
+	case ByWeek:
+		year, week := p.From.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case ByMonth:
+		return p.From.Format("2006-01")
+	default:
+		return p.From.Format("2006-01-02 Mon")
+	}
+}
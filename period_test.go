@@ -0,0 +1,258 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDateRangePeriod(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    string
+		to      string
+		want    Period
+		wantErr bool
+	}{
+		{
+			name: "Unbounded",
+			want: Period{},
+		},
+		{
+			name: "FromOnly",
+			from: "2024-01-15",
+			want: Period{From: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "ToIsInclusive",
+			to:   "2024-12-31",
+			want: Period{To: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "SingleDay",
+			from: "2024-02-29",
+			to:   "2024-02-29",
+			want: Period{From: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), To: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:    "InvalidDate",
+			from:    "2024-13-01",
+			wantErr: true,
+		},
+		{
+			name:    "FromAfterTo",
+			from:    "2024-02-01",
+			to:      "2024-01-01",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DateRangePeriod(tt.from, tt.to, time.UTC)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DateRangePeriod() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("DateRangePeriod() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPeriodContains(t *testing.T) {
+	period := Period{
+		From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+	}
+	tests := []struct {
+		name   string
+		period Period
+		time   time.Time
+		want   bool
+	}{
+		{name: "Start", period: period, time: period.From, want: true},
+		{name: "Inside", period: period, time: time.Date(2024, 1, 31, 23, 59, 59, 0, time.UTC), want: true},
+		{name: "End", period: period, time: period.To, want: false},
+		{name: "Before", period: period, time: time.Date(2023, 12, 31, 23, 59, 59, 0, time.UTC), want: false},
+		{name: "Unbounded", period: Period{}, time: time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.period.Contains(tt.time); got != tt.want {
+				t.Errorf("Period.Contains() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsoWeekPeriod(t *testing.T) {
+	tests := []struct {
+		name    string
+		week    int
+		year    int
+		from    time.Time
+		wantErr bool
+	}{
+		{name: "FirstWeekStartsInPreviousYear", week: 1, year: 2025, from: time.Date(2024, 12, 30, 0, 0, 0, 0, time.UTC)},
+		{name: "FirstWeekStartsInSameYear", week: 1, year: 2024, from: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "FirstWeekStartsAfterNewYear", week: 1, year: 2021, from: time.Date(2021, 1, 4, 0, 0, 0, 0, time.UTC)},
+		{name: "Week53EndsInNextYear", week: 53, year: 2020, from: time.Date(2020, 12, 28, 0, 0, 0, 0, time.UTC)},
+		{name: "LastWeekOf52WeekYear", week: 52, year: 2025, from: time.Date(2025, 12, 22, 0, 0, 0, 0, time.UTC)},
+		{name: "Week53InYearWith52Weeks", week: 53, year: 2025, wantErr: true},
+		{name: "WeekZero", week: 0, year: 2025, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := IsoWeekPeriod(tt.week, tt.year, time.UTC)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("IsoWeekPeriod() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			want := Period{From: tt.from, To: tt.from.AddDate(0, 0, 7)}
+			if got != want {
+				t.Errorf("IsoWeekPeriod() = %v, want %v", got, want)
+			}
+			if year, week := got.From.ISOWeek(); year != tt.year || week != tt.week {
+				t.Errorf("IsoWeekPeriod() starts in ISO week %d-W%d, want %d-W%d", year, week, tt.year, tt.week)
+			}
+		})
+	}
+}
+
+func TestQuarterPeriod(t *testing.T) {
+	got, err := QuarterPeriod(4, 2024, time.UTC)
+	if err != nil {
+		t.Fatalf("QuarterPeriod() error = %v", err)
+	}
+	want := Period{From: time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	if got != want {
+		t.Errorf("QuarterPeriod() = %v, want %v", got, want)
+	}
+
+	if _, err := QuarterPeriod(5, 2024, time.UTC); err == nil {
+		t.Errorf("QuarterPeriod() expected error for quarter 5")
+	}
+}
+
+func TestParseQuarter(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{value: "Q1", want: 1},
+		{value: "q3", want: 3},
+		{value: "4", want: 4},
+		{value: "Q0", wantErr: true},
+		{value: "Q5", wantErr: true},
+		{value: "third", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseQuarter(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseQuarter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseQuarter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLastPeriod(t *testing.T) {
+	now := time.Date(2025, 1, 10, 15, 30, 0, 0, time.UTC)
+	tomorrow := time.Date(2025, 1, 11, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		length  string
+		from    time.Time
+		wantErr bool
+	}{
+		{length: "1d", from: time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)},
+		{length: "30d", from: time.Date(2024, 12, 12, 0, 0, 0, 0, time.UTC)},
+		{length: "2w", from: time.Date(2024, 12, 28, 0, 0, 0, 0, time.UTC)},
+		{length: "3m", from: time.Date(2024, 10, 11, 0, 0, 0, 0, time.UTC)},
+		{length: "1y", from: time.Date(2024, 1, 11, 0, 0, 0, 0, time.UTC)},
+		{length: "0d", wantErr: true},
+		{length: "d", wantErr: true},
+		{length: "10h", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.length, func(t *testing.T) {
+			got, err := LastPeriod(tt.length, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LastPeriod() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			want := Period{From: tt.from, To: tomorrow}
+			if got != want {
+				t.Errorf("LastPeriod() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestPeriodSplit(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name   string
+		period Period
+		by     Granularity
+		want   []string
+	}{
+		{
+			name:   "MonthsOfYear",
+			period: YearPeriod(2024, time.UTC),
+			by:     ByMonth,
+			want:   []string{"2024-01", "2024-02", "2024-03", "2024-04", "2024-05", "2024-06", "2024-07", "2024-08", "2024-09", "2024-10", "2024-11", "2024-12"},
+		},
+		{
+			name:   "WeeksOfMonthAreClipped",
+			period: MonthPeriod(time.December, 2024, time.UTC),
+			by:     ByWeek,
+			want:   []string{"2024-W48", "2024-W49", "2024-W50", "2024-W51", "2024-W52", "2025-W01"},
+		},
+		{
+			name:   "DaysOfWeek",
+			period: Period{From: date(2024, 12, 30), To: date(2025, 1, 6)},
+			by:     ByDay,
+			want:   []string{"2024-12-30 Mon", "2024-12-31 Tue", "2025-01-01 Wed", "2025-01-02 Thu", "2025-01-03 Fri", "2025-01-04 Sat", "2025-01-05 Sun"},
+		},
+		{
+			name:   "EmptyPeriod",
+			period: Period{From: date(2024, 1, 1), To: date(2024, 1, 1)},
+			by:     ByDay,
+			want:   []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts := tt.period.Split(tt.by)
+			labels := make([]string, 0)
+			for i, part := range parts {
+				labels = append(labels, part.Label(tt.by))
+				if i > 0 && part.From != parts[i-1].To {
+					t.Errorf("Period.Split() part %d starts at %v, previous ends at %v", i, part.From, parts[i-1].To)
+				}
+			}
+			if len(parts) > 0 && (parts[0].From != tt.period.From || parts[len(parts)-1].To != tt.period.To) {
+				t.Errorf("Period.Split() = %v, does not cover %v", parts, tt.period)
+			}
+			if !reflect.DeepEqual(labels, tt.want) {
+				t.Errorf("Period.Split() labels = %v, want %v", labels, tt.want)
+			}
+		})
+	}
+}
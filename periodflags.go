@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"strconv"
+	"time"
+)
+
+var conflictingPeriodFlags = errors.New("only one of --month, --week, --quarter, --from/--to and --last can be set, --year can be combined with --month, --week and --quarter only")
+
+// periodFlags registers the common set of flags selecting a reporting period.
+type periodFlags struct {
+	flags   *flag.FlagSet
+	now     time.Time
+	month   *int
+	year    *int
+	week    *int
+	quarter *string
+	from    *string
+	to      *string
+	last    *string
+}
+
+// addPeriodFlags defines period flags on the flag set, action is used in flag descriptions, e.g. "show total expenses".
+func addPeriodFlags(flags *flag.FlagSet, action string, now time.Time) *periodFlags {
+	_, currentWeek := now.ISOWeek()
+	week := weekValue{week: new(int), current: currentWeek}
+	flags.Var(week, "week", action+" for the specified ISO `week` (1-53) of the ISO week-numbering year, the current week without a number")
+	return &periodFlags{
+		flags:   flags,
+		now:     now,
+		month:   flags.Int("month", int(now.Month()), action+" for the specified month (1-12)"),
+		year:    flags.Int("year", now.Year(), action+" for the specified year, or year of the specified month, week or quarter"),
+		week:    week.week,
+		quarter: flags.String("quarter", "", action+" for the specified quarter (Q1-Q4)"),
+		from:    flags.String("from", "", action+" starting from the specified date (YYYY-MM-DD)"),
+		to:      flags.String("to", "", action+" up to and including the specified date (YYYY-MM-DD)"),
+		last:    flags.String("last", "", action+" for the last period ending today, e.g. 30d, 4w, 6m or 1y"),
+	}
+}
+
+// weekValue is the --week flag, passed without a number it selects the current week.
+type weekValue struct {
+	week    *int
+	current int
+}
+
+func (v weekValue) String() string {
+	if v.week == nil {
+		return "0"
+	}
+	return strconv.Itoa(*v.week)
+}
+
+func (v weekValue) Set(value string) error {
+	week, err := strconv.Atoi(value)
+	if err != nil {
+		return errors.New("invalid week")
+	}
+	*v.week = week
+	return nil
+}
+
+func (v weekValue) BareValue() string {
+	return strconv.Itoa(v.current)
+}
+
+// Period returns selected period, ok is false when no period flag was passed.
+func (p *periodFlags) Period(loc *time.Location) (period Period, ok bool, err error) {
+	isMonthPassed := isFlagPassed(p.flags, "month")
+	isYearPassed := isFlagPassed(p.flags, "year")
+	isWeekPassed := isFlagPassed(p.flags, "week")
+	isQuarterPassed := isFlagPassed(p.flags, "quarter")
+	isRangePassed := isFlagPassed(p.flags, "from") || isFlagPassed(p.flags, "to")
+	isLastPassed := isFlagPassed(p.flags, "last")
+
+	selected := 0
+	for _, passed := range []bool{isMonthPassed, isWeekPassed, isQuarterPassed, isRangePassed, isLastPassed} {
+		if passed {
+			selected++
+		}
+	}
+	if selected > 1 || (isYearPassed && (isRangePassed || isLastPassed)) {
+		return Period{}, false, conflictingPeriodFlags
+	}
+	if selected == 0 && !isYearPassed {
+		return Period{}, false, nil
+	}
+
+	if isYearPassed && (*p.year < 1970 || *p.year > 9999) {
+		return Period{}, false, errors.New("invalid year")
+	}
+
+	switch {
+	case isMonthPassed:
+		if *p.month < 1 || *p.month > 12 {
+			return Period{}, false, errors.New("invalid month")
+		}
+		return MonthPeriod(time.Month(*p.month), *p.year, loc), true, nil
+	case isWeekPassed:
+		year := *p.year
+		if !isYearPassed {
+			year, _ = p.now.In(loc).ISOWeek()
+		}
+		period, err = IsoWeekPeriod(*p.week, year, loc)
+		return period, err == nil, err
+	case isQuarterPassed:
+		quarter, err := ParseQuarter(*p.quarter)
+		if err != nil {
+			return Period{}, false, err
+		}
+		period, err = QuarterPeriod(quarter, *p.year, loc)
+		return period, err == nil, err
+	case isRangePassed:
+		period, err = DateRangePeriod(*p.from, *p.to, loc)
+		return period, err == nil, err
+	case isLastPassed:
+		period, err = LastPeriod(*p.last, p.now.In(loc))
+		return period, err == nil, err
+	default:
+		return YearPeriod(*p.year, loc), true, nil
+	}
+}
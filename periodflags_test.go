@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"testing"
+	"time"
+)
+
+func TestPeriodFlags(t *testing.T) {
+	now := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		args    []string
+		want    Period
+		wantOk  bool
+		wantErr bool
+	}{
+		{
+			name:   "NoFlags",
+			args:   []string{},
+			wantOk: false,
+		},
+		{
+			name:   "MonthOfCurrentYear",
+			args:   []string{"--month", "12"},
+			want:   Period{From: time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+			wantOk: true,
+		},
+		{
+			name:   "Year",
+			args:   []string{"--year", "2024"},
+			want:   Period{From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+			wantOk: true,
+		},
+		{
+			name:   "WeekDefaultsToCurrentIsoYear",
+			args:   []string{"--week", "1"},
+			want:   Period{From: time.Date(2024, 12, 30, 0, 0, 0, 0, time.UTC), To: time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)},
+			wantOk: true,
+		},
+		{
+			name:   "CurrentWeek",
+			args:   []string{"--week"},
+			want:   Period{From: time.Date(2024, 12, 30, 0, 0, 0, 0, time.UTC), To: time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)},
+			wantOk: true,
+		},
+		{
+			name:   "CurrentWeekNumberOfYear",
+			args:   []string{"--week", "--year", "2024"},
+			want:   Period{From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)},
+			wantOk: true,
+		},
+		{
+			name:   "WeekWithEquals",
+			args:   []string{"--week=2", "--year", "2024"},
+			want:   Period{From: time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC), To: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)},
+			wantOk: true,
+		},
+		{
+			name:   "QuarterOfYear",
+			args:   []string{"--quarter", "Q3", "--year", "2024"},
+			want:   Period{From: time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)},
+			wantOk: true,
+		},
+		{
+			name:   "DateRange",
+			args:   []string{"--from", "2024-12-01", "--to", "2024-12-31"},
+			want:   Period{From: time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+			wantOk: true,
+		},
+		{
+			name:   "Last",
+			args:   []string{"--last", "7d"},
+			want:   Period{From: time.Date(2024, 12, 26, 0, 0, 0, 0, time.UTC), To: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)},
+			wantOk: true,
+		},
+		{name: "InvalidMonth", args: []string{"--month", "13"}, wantErr: true},
+		{name: "InvalidYear", args: []string{"--year", "1900"}, wantErr: true},
+		{name: "MonthAndWeek", args: []string{"--month", "1", "--week", "1"}, wantErr: true},
+		{name: "YearAndLast", args: []string{"--year", "2024", "--last", "7d"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags := flag.NewFlagSet("test", flag.ContinueOnError)
+			periodFlags := addPeriodFlags(flags, "test", now)
+			if err := parseFlags(flags, tt.args); err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			got, ok, err := periodFlags.Period(time.UTC)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("periodFlags.Period() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if ok != tt.wantOk {
+				t.Errorf("periodFlags.Period() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if got != tt.want {
+				t.Errorf("periodFlags.Period() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
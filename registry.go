@@ -0,0 +1,406 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Command describes a command line command, the registry is the single source for running commands,
+// help and usage texts, completions in the shell and in completion scripts.
+type Command struct {
+	Name    string
+	Aliases []string
+	// one line description shown in completions
+	Summary string
+	// arguments shown after the command name in usage
+	Synopsis string
+	// detailed description shown in usage of the command
+	Description string
+	// values of positional arguments
+	Args []string
+	// hidden commands are not shown in help and completions
+	Hidden bool
+	// commands without tracker run without loading the ledger and get nil tracker
+	NoTracker bool
+	// commands that may replace the ledger file, the shell loads the tracker again after them
+	ReplacesLedger bool
+	// Setup defines flags of the command and returns the action run after flags are parsed
+	Setup func(flags *flag.FlagSet, env *Env) func(tracker *Tracker) error
+}
+
+// Commands is initialized in init to allow commands refer to the registry.
+var Commands []Command
+
+func init() {
+	Commands = []Command{
+		{
+			Name:        "help",
+			Summary:     "show usage information",
+			Synopsis:    "[<command>]",
+			Description: "show usage of all commands or detailed usage of the specified command",
+			NoTracker:   true,
+			Setup:       HelpCmd,
+		},
+		{
+			Name:        "add",
+			Summary:     "add a new record",
+			Synopsis:    "--description <description> --amount <amount> [--income] [--account <account>] [--tag <tag>]... [--payee <payee>] [--notes <notes>]",
+			Description: "add a new expense to the tracker, or income with --income, tags like business or trip-lisbon group records across categories",
+			Setup:       AddCmd,
+		},
+		{
+			Name:        "update",
+			Aliases:     []string{"edit"},
+			Summary:     "update description, amount, tags, payee, notes or account of a record",
+			Synopsis:    "--id <id> [--description <description>] [--amount <amount>] [--tag <tag>]... [--untag <tag>]... [--payee <payee>] [--notes <notes>] [--account <account>]",
+			Description: "set new description, amount, payee, notes or account to record with specified id, add or remove its tags, at least one optional parameter must be specified, changing amount of a transfer changes both of its records",
+			Setup:       UpdateCmd,
+		},
+		{
+			Name:        "delete",
+			Aliases:     []string{"rm"},
+			Summary:     "delete a record",
+			Synopsis:    "--id <id>",
+			Description: "delete record with specified id, files attached to it are kept for its backups, remove unused files with attachments --prune",
+			Setup:       DeleteCmd,
+		},
+		{
+			Name:        "attach",
+			Summary:     "attach files to a record",
+			Synopsis:    "--id <id> <file>...",
+			Description: "copy files, e.g. receipts, to the attachments directory next to the ledger and link them to the record, files with the same content are stored once",
+			Setup:       AttachCmd,
+		},
+		{
+			Name:        "attachments",
+			Summary:     "list files attached to a record",
+			Synopsis:    "--id <id> [--output <directory>] | --prune",
+			Description: "list files attached to the record, --output copies them to the directory, --prune removes stored files no record of the ledger or of its backups links to, files are not removed when records are deleted because a restored backup may still link to them",
+			Setup:       AttachmentsCmd,
+		},
+		{
+			Name:        "list",
+			Aliases:     []string{"ls"},
+			Summary:     "list all records",
+			Synopsis:    "[--tag <tag>]... [--any-tag <tag>]... [--search <text>] [--account <account>]",
+			Description: "list all records, or records having all tags of --tag and at least one tag of --any-tag, --search selects records containing the text in description, payee, notes or tags ignoring case, --account selects records of the account",
+			Setup:       ListCmd,
+		},
+		{
+			Name:        "summary",
+			Summary:     "show total expenses",
+			Synopsis:    "[--tag <tag>]... [--any-tag <tag>]... [<period>]",
+			Description: "show total expenses for all time, with total income and net when there was income, can set optional parameters to show totals for specified period and tags",
+			Setup:       SummaryCmd,
+		},
+		{
+			Name:        "report",
+			Summary:     "show totals per day, week or month",
+			Synopsis:    "[--by day|week|month] [<period>]",
+			Description: "show totals, counts and averages per day, week or month of a period, by default months of the current year, weeks of the current month or days of the current week",
+			Setup:       ReportCmd,
+		},
+		{
+			Name:        "chart",
+			Summary:     "draw spending charts",
+			Synopsis:    "[--by day|week|month] [--width <number>] [<period>]",
+			Description: "draw spending per day, week or month of a period as a sparkline and a bar chart, by default months of the current year, weeks of the current month or days of the current week",
+			Setup:       ChartCmd,
+		},
+		{
+			Name:        "stats",
+			Summary:     "show spending statistics",
+			Synopsis:    "[--top <number>] [<period>]",
+			Description: "show statistics of expenses for all time, can set optional parameters to show statistics for specified period",
+			Setup:       StatsCmd,
+		},
+		{
+			Name:        "tags",
+			Summary:     "list tags with usage counts and totals",
+			Synopsis:    "[<period>]",
+			Description: "list all tags with number of expenses and their total, can set optional parameters to count records of specified period",
+			Setup:       TagsCmd,
+		},
+		{
+			Name:        "payees",
+			Summary:     "show spending per payee",
+			Synopsis:    "[<period>]",
+			Description: "show number of expenses and their total per payee, largest first, can set optional parameters to show spending for specified period",
+			Setup:       PayeesCmd,
+		},
+		{
+			Name:        "accounts",
+			Summary:     "show account balances",
+			Description: "show income, expenses, transfers and balance of every account, records without account belong to the default account",
+			Setup:       AccountsCmd,
+		},
+		{
+			Name:        "transfer",
+			Summary:     "move money between accounts",
+			Synopsis:    "--from <account> --to <account> --amount <amount> [--description <description>]",
+			Description: "add two linked records moving money from one account to another, transfers are not counted as income or expenses, deleting one of the records deletes both",
+			Setup:       TransferCmd,
+		},
+		{
+			Name:        "serve",
+			Summary:     "serve HTTP API and web interface",
+			Synopsis:    "[--addr <address>]",
+			Description: "serve the tracker over HTTP with JSON API, stops on interrupt signal",
+			Setup:       ServeCmd,
+		},
+		{
+			Name:        "shell",
+			Summary:     "start interactive shell",
+			Description: "start interactive shell running commands against the loaded tracker, type exit or press Ctrl+D to quit",
+			Setup:       ShellCmd,
+		},
+		{
+			Name:        "tui",
+			Summary:     "start full-screen terminal interface",
+			Description: "start full-screen terminal interface to browse, filter, add, edit and delete records",
+			Setup:       TuiCmd,
+		},
+		{
+			Name:        "completion",
+			Summary:     "print shell completion script",
+			Synopsis:    "bash|zsh|fish",
+			Description: "print completion script for bash, zsh or fish, e.g. add to ~/.bashrc:\n  source <(expense-tracker completion bash)",
+			Args:        completionShells,
+			NoTracker:   true,
+			Setup:       CompletionCmd,
+		},
+		{
+			Name:        "export",
+			Summary:     "export records to csv, json or xlsx file",
+			Synopsis:    "--output <file> [--format csv|json|xlsx] [--delimiter <char>] [--date-format <layout>] [--tag <tag>]... [--any-tag <tag>]... [<period>]",
+			Description: "export records to a csv, json or xlsx file, can set optional parameters to export only records for specified period or with specified tags",
+			Setup:       ExportCmd,
+		},
+		{
+			Name:           "encrypt",
+			Summary:        "encrypt the ledger with a passphrase",
+			Synopsis:       "[--input <file>] [--output <file>] [--keep]",
+			Description:    "encrypt a plaintext ledger with AES-GCM using a key derived from a passphrase, the passphrase is read from " + passphraseVariable + " or asked in the terminal, the plaintext file is removed unless --keep is set",
+			NoTracker:      true,
+			ReplacesLedger: true,
+			Setup:          EncryptCmd,
+		},
+		{
+			Name:           "decrypt",
+			Summary:        "decrypt the ledger to plaintext csv",
+			Synopsis:       "[--input <file>] [--output <file>] [--keep]",
+			Description:    "decrypt an encrypted ledger back to a plaintext csv file, the encrypted file is removed unless --keep is set",
+			NoTracker:      true,
+			ReplacesLedger: true,
+			Setup:          DecryptCmd,
+		},
+		{
+			Name:           "backup",
+			Summary:        "list or restore ledger backups",
+			Synopsis:       "list|restore <name>",
+			Description:    backupDescription,
+			Args:           []string{"list", "restore"},
+			NoTracker:      true,
+			ReplacesLedger: true,
+			Setup:          BackupCmd,
+		},
+		{
+			Name:           "doctor",
+			Summary:        "check the ledger file and repair it",
+			Synopsis:       "[--file <file>] [--repair] [--quarantine <file>]",
+			Description:    "check the ledger for malformed rows, duplicate IDs, zero amounts, non-monotonic IDs and future dates, with --repair write the ledger sorted by ID and move bad rows to the quarantine file, the previous ledger is backed up",
+			NoTracker:      true,
+			ReplacesLedger: true,
+			Setup:          DoctorCmd,
+		},
+		{
+			Name:      completeCommandName,
+			Hidden:    true,
+			NoTracker: true,
+			Setup:     CompleteCmd,
+		},
+	}
+}
+
+// Run parses flags of the command and runs it against the tracker.
+func (c Command) Run(args []string, env *Env, tracker *Tracker) error {
+	flags := c.flagSet()
+	flags.SetOutput(env.Stderr)
+	run := c.Setup(flags, env)
+	err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+	return run(tracker)
+}
+
+// optionalValue is a flag value that can be passed without an argument, e.g. bare --week for the current week.
+type optionalValue interface {
+	flag.Value
+	// BareValue is set when the flag is passed without an argument
+	BareValue() string
+}
+
+// parseFlags parses args, a flag with optional value followed by another flag or by nothing gets its bare value,
+// the flag package would take the next argument as its value otherwise.
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	expanded := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		expanded = append(expanded, arg)
+		// flags end at the first positional argument or at --
+		if arg == "--" || len(arg) < 2 || arg[0] != '-' {
+			expanded = append(expanded, args[i+1:]...)
+			break
+		}
+		name := strings.TrimLeft(arg, "-")
+		f := flags.Lookup(name)
+		if strings.Contains(name, "=") || f == nil {
+			continue
+		}
+		if boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && boolFlag.IsBoolFlag() {
+			continue
+		}
+		hasArgument := i+1 < len(args) && (len(args[i+1]) < 2 || args[i+1][0] != '-')
+		if optional, ok := f.Value.(optionalValue); ok && !hasArgument {
+			expanded[len(expanded)-1] = arg + "=" + optional.BareValue()
+			continue
+		}
+		// the next argument is the value even if it starts with a dash
+		if i+1 < len(args) {
+			i++
+			expanded = append(expanded, args[i])
+		}
+	}
+	return flags.Parse(expanded)
+}
+
+// FlagNames returns flags of the command with leading dashes, flags are defined in the environment the command runs in.
+func (c Command) FlagNames(env *Env) []string {
+	flags := c.flagSet()
+	c.Setup(flags, env)
+	names := make([]string, 0)
+	flags.VisitAll(func(f *flag.Flag) {
+		names = append(names, "--"+f.Name)
+	})
+	return names
+}
+
+// PrintUsage prints detailed usage of the command with its flags and their defaults in the environment.
+func (c Command) PrintUsage(w io.Writer, env *Env) {
+	flags := c.flagSet()
+	c.Setup(flags, env)
+	flags.SetOutput(w)
+	flags.Usage()
+}
+
+func (c Command) flagSet() *flag.FlagSet {
+	flags := flag.NewFlagSet(c.Name, flag.ContinueOnError)
+	flags.Usage = func() {
+		w := flags.Output()
+		fmt.Fprintf(w, "Usage: %s\n\n%s\n", c.usageLine(), c.Description)
+		if len(c.Aliases) > 0 {
+			fmt.Fprintf(w, "\nAliases: %s\n", strings.Join(c.Aliases, ", "))
+		}
+		if strings.Contains(c.Synopsis, "<period>") {
+			fmt.Fprintf(w, "\n%s", periodHelp)
+		}
+		hasFlags := false
+		flags.VisitAll(func(_ *flag.Flag) {
+			hasFlags = true
+		})
+		if hasFlags {
+			fmt.Fprint(w, "\nOptions:\n")
+			flags.PrintDefaults()
+		}
+	}
+	return flags
+}
+
+func (c Command) usageLine() string {
+	if c.Synopsis == "" {
+		return "expense-tracker " + c.Name
+	}
+	return "expense-tracker " + c.Name + " " + c.Synopsis
+}
+
+func findCommand(name string) (Command, bool) {
+	for _, command := range Commands {
+		if command.Name == name {
+			return command, true
+		}
+		for _, alias := range command.Aliases {
+			if alias == name {
+				return command, true
+			}
+		}
+	}
+	return Command{}, false
+}
+
+// lookupCommand finds a command by name or alias, the error suggests the closest command for misspelled names.
+func lookupCommand(name string) (Command, error) {
+	command, ok := findCommand(name)
+	if ok {
+		return command, nil
+	}
+	suggestion, ok := suggestCommand(name)
+	if ok {
+		return Command{}, fmt.Errorf("unknown command %q, did you mean %q?", name, suggestion)
+	}
+	return Command{}, fmt.Errorf("unknown command %q", name)
+}
+
+// suggestCommand returns the visible command or alias closest to name by edit distance,
+// names starting with name are preferred.
+func suggestCommand(name string) (string, bool) {
+	best := ""
+	bestDistance := 0
+	for _, command := range visibleCommands(Commands) {
+		for _, candidate := range append([]string{command.Name}, command.Aliases...) {
+			distance := editDistance(name, candidate)
+			if len(name) > 1 && strings.HasPrefix(candidate, name) {
+				distance = 0
+			}
+			if best == "" || distance < bestDistance {
+				best = candidate
+				bestDistance = distance
+			}
+		}
+	}
+	// allow a typo per three characters, at least one
+	maxDistance := max(1, len([]rune(name))/3)
+	if best == "" || bestDistance > maxDistance {
+		return "", false
+	}
+	return best, true
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	previous := make([]int, len(br)+1)
+	current := make([]int, len(br)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		current[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(br)]
+}
+
+// printUnknownCommand reports an unknown command with a suggestion.
+func printUnknownCommand(w io.Writer, err error) {
+	fmt.Fprintf(w, "%v\nrun 'expense-tracker help' for usage\n", err)
+}
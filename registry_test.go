@@ -0,0 +1,106 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFindCommand(t *testing.T) {
+	tests := []struct {
+		name   string
+		want   string
+		wantOk bool
+	}{
+		{name: "list", want: "list", wantOk: true},
+		{name: "ls", want: "list", wantOk: true},
+		{name: "rm", want: "delete", wantOk: true},
+		{name: "edit", want: "update", wantOk: true},
+		{name: "lst", wantOk: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			command, ok := findCommand(tt.name)
+			if ok != tt.wantOk {
+				t.Fatalf("findCommand() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if ok && command.Name != tt.want {
+				t.Errorf("findCommand() = %q, want %q", command.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestSuggestCommand(t *testing.T) {
+	tests := []struct {
+		name   string
+		want   string
+		wantOk bool
+	}{
+		{name: "lst", want: "list", wantOk: true},
+		{name: "sumary", want: "summary", wantOk: true},
+		{name: "delte", want: "delete", wantOk: true},
+		{name: "exprot", want: "export", wantOk: true},
+		{name: "comp", want: "completion", wantOk: true},
+		{name: "xyz", wantOk: false},
+		{name: "__complet", wantOk: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := suggestCommand(tt.name)
+			if ok != tt.wantOk {
+				t.Fatalf("suggestCommand() = %q, %v, want ok %v", got, ok, tt.wantOk)
+			}
+			if ok && got != tt.want {
+				t.Errorf("suggestCommand() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "", b: "", want: 0},
+		{a: "list", b: "list", want: 0},
+		{a: "", b: "add", want: 3},
+		{a: "lst", b: "list", want: 1},
+		{a: "exprot", b: "export", want: 2},
+		{a: "kitten", b: "sitting", want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"_"+tt.b, func(t *testing.T) {
+			if got := editDistance(tt.a, tt.b); got != tt.want {
+				t.Errorf("editDistance() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHelpText(t *testing.T) {
+	text := HelpText()
+	for _, command := range visibleCommands(Commands) {
+		if command.Name == "help" {
+			continue
+		}
+		if !strings.Contains(text, command.usageLine()+"\n") {
+			t.Errorf("HelpText() does not contain usage of %q", command.Name)
+		}
+	}
+	if strings.Contains(text, completeCommandName) {
+		t.Errorf("HelpText() contains hidden command %q", completeCommandName)
+	}
+}
+
+func TestCommandFlagNames(t *testing.T) {
+	command, _ := findCommand("update")
+	got := strings.Join(command.FlagNames(flagsTestEnv()), " ")
+	want := "--account --amount --description --id --notes --payee --tag --untag"
+	if got != want {
+		t.Errorf("FlagNames() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/bits"
+)
+
+var invalidScryptParams = errors.New("invalid scrypt parameters")
+
+// pbkdf2Sha256 derives a key of keyLen bytes with PBKDF2-HMAC-SHA256 as defined in RFC 8018.
+func pbkdf2Sha256(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	key := make([]byte, 0, keyLen)
+	block := make([]byte, 4)
+	var u, t []byte
+	for i := uint32(1); len(key) < keyLen; i++ {
+		binary.BigEndian.PutUint32(block, i)
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write(block)
+		u = prf.Sum(u[:0])
+		t = append(t[:0], u...)
+		for n := 1; n < iterations; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLen]
+}
+
+// scrypt derives a key of keyLen bytes as defined in RFC 7914,
+// n is the CPU/memory cost and must be a power of two, memory use is 128*r*n bytes.
+func scrypt(password, salt []byte, n, r, p, keyLen int) ([]byte, error) {
+	if n <= 1 || n&(n-1) != 0 || r <= 0 || p <= 0 || uint64(r)*uint64(p) >= 1<<30 || n > 1<<24 {
+		return nil, invalidScryptParams
+	}
+
+	blockLen := 128 * r
+	b := pbkdf2Sha256(password, salt, 1, p*blockLen)
+	x := make([]uint32, 32*r)
+	v := make([]uint32, 32*r*n)
+	y := make([]uint32, 32*r)
+	for i := 0; i < p; i++ {
+		block := b[i*blockLen : (i+1)*blockLen]
+		for j := range x {
+			x[j] = binary.LittleEndian.Uint32(block[j*4:])
+		}
+		scryptRoMix(x, v, y, r, n)
+		for j := range x {
+			binary.LittleEndian.PutUint32(block[j*4:], x[j])
+		}
+	}
+	return pbkdf2Sha256(password, b, 1, keyLen), nil
+}
+
+// scryptRoMix mixes block x in place using v as n blocks of scratch memory and y as a temporary block.
+func scryptRoMix(x, v, y []uint32, r, n int) {
+	blockWords := 32 * r
+	for i := 0; i < n; i++ {
+		copy(v[i*blockWords:], x)
+		scryptBlockMix(x, y, r)
+	}
+	for i := 0; i < n; i++ {
+		j := int(x[(2*r-1)*16] & uint32(n-1))
+		for k := range x {
+			x[k] ^= v[j*blockWords+k]
+		}
+		scryptBlockMix(x, y, r)
+	}
+}
+
+// scryptBlockMix applies Salsa20/8 to 2*r 64-byte chunks of b, y is a temporary block of the same size.
+func scryptBlockMix(b, y []uint32, r int) {
+	var chunk [16]uint32
+	copy(chunk[:], b[(2*r-1)*16:])
+	for i := 0; i < 2*r; i++ {
+		for j := range chunk {
+			chunk[j] ^= b[i*16+j]
+		}
+		salsa208(&chunk)
+		// even chunks go to the first half, odd chunks to the second
+		offset := (i/2)*16 + (i%2)*r*16
+		copy(y[offset:], chunk[:])
+	}
+	copy(b, y)
+}
+
+func salsa208(b *[16]uint32) {
+	x := *b
+	for i := 0; i < 8; i += 2 {
+		x[4] ^= bits.RotateLeft32(x[0]+x[12], 7)
+		x[8] ^= bits.RotateLeft32(x[4]+x[0], 9)
+		x[12] ^= bits.RotateLeft32(x[8]+x[4], 13)
+		x[0] ^= bits.RotateLeft32(x[12]+x[8], 18)
+		x[9] ^= bits.RotateLeft32(x[5]+x[1], 7)
+		x[13] ^= bits.RotateLeft32(x[9]+x[5], 9)
+		x[1] ^= bits.RotateLeft32(x[13]+x[9], 13)
+		x[5] ^= bits.RotateLeft32(x[1]+x[13], 18)
+		x[14] ^= bits.RotateLeft32(x[10]+x[6], 7)
+		x[2] ^= bits.RotateLeft32(x[14]+x[10], 9)
+		x[6] ^= bits.RotateLeft32(x[2]+x[14], 13)
+		x[10] ^= bits.RotateLeft32(x[6]+x[2], 18)
+		x[3] ^= bits.RotateLeft32(x[15]+x[11], 7)
+		x[7] ^= bits.RotateLeft32(x[3]+x[15], 9)
+		x[11] ^= bits.RotateLeft32(x[7]+x[3], 13)
+		x[15] ^= bits.RotateLeft32(x[11]+x[7], 18)
+
+		x[1] ^= bits.RotateLeft32(x[0]+x[3], 7)
+		x[2] ^= bits.RotateLeft32(x[1]+x[0], 9)
+		x[3] ^= bits.RotateLeft32(x[2]+x[1], 13)
+		x[0] ^= bits.RotateLeft32(x[3]+x[2], 18)
+		x[6] ^= bits.RotateLeft32(x[5]+x[4], 7)
+		x[7] ^= bits.RotateLeft32(x[6]+x[5], 9)
+		x[4] ^= bits.RotateLeft32(x[7]+x[6], 13)
+		x[5] ^= bits.RotateLeft32(x[4]+x[7], 18)
+		x[11] ^= bits.RotateLeft32(x[10]+x[9], 7)
+		x[8] ^= bits.RotateLeft32(x[11]+x[10], 9)
+		x[9] ^= bits.RotateLeft32(x[8]+x[11], 13)
+		x[10] ^= bits.RotateLeft32(x[9]+x[8], 18)
+		x[12] ^= bits.RotateLeft32(x[15]+x[14], 7)
+		x[13] ^= bits.RotateLeft32(x[12]+x[15], 9)
+		x[14] ^= bits.RotateLeft32(x[13]+x[12], 13)
+		x[15] ^= bits.RotateLeft32(x[14]+x[13], 18)
+	}
+	for i := range b {
+		b[i] += x[i]
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestPbkdf2Sha256(t *testing.T) {
+	tests := []struct {
+		name       string
+		password   string
+		salt       string
+		iterations int
+		keyLen     int
+		want       string
+	}{
+		{
+			name:     "Rfc7914",
+			password: "passwd", salt: "salt", iterations: 1, keyLen: 64,
+			want: "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783",
+		},
+		{
+			name:     "Rfc7914ManyIterations",
+			password: "Password", salt: "NaCl", iterations: 80000, keyLen: 64,
+			want: "4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56a1d425a1225833549adb841b51c9b3176a272bdebba1d078478f62b397f33c8d",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := hex.EncodeToString(pbkdf2Sha256([]byte(tt.password), []byte(tt.salt), tt.iterations, tt.keyLen))
+			if got != tt.want {
+				t.Errorf("pbkdf2Sha256() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScrypt(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		salt     string
+		n, r, p  int
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "Rfc7914Empty",
+			password: "", salt: "", n: 16, r: 1, p: 1,
+			want: "77d6576238657b203b19ca42c18a0497f16b4844e3074ae8dfdffa3fede21442fcd0069ded0948f8326a753a0fc81f17e8d3e0fb2e0d3628cf35e20c38d18906",
+		},
+		{
+			name:     "Rfc7914Password",
+			password: "password", salt: "NaCl", n: 1024, r: 8, p: 16,
+			want: "fdbabe1c9d3472007856e7190d01e9fe7c6ad7cbc8237830e77376634b3731622eaf30d92e22a3886ff109279d9830dac727afb94a83ee6d8360cbdfa2cc0640",
+		},
+		{name: "NotPowerOfTwo", n: 1000, r: 8, p: 1, wantErr: true},
+		{name: "ZeroR", n: 16, r: 0, p: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := scrypt([]byte(tt.password), []byte(tt.salt), tt.n, tt.r, tt.p, 64)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("scrypt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := hex.EncodeToString(key); !tt.wantErr && got != tt.want {
+				t.Errorf("scrypt() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
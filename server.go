@@ -0,0 +1,279 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const maxRequestBodySize = 1 << 20
+
+// requiring JSON bodies keeps other sites from changing records with plain HTML forms
+var unsupportedMediaType = errors.New("request body must be application/json")
+
+// web interface, plain HTML and JavaScript talking to the JSON API
+//
+//go:embed web
+var webFiles embed.FS
+
+type Server struct {
+	tracker *Tracker
+	mux     *http.ServeMux
+}
+
+func NewServer(tracker *Tracker) *Server {
+	s := &Server{tracker: tracker, mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /expenses", s.listExpenses)
+	s.mux.HandleFunc("POST /expenses", s.addExpense)
+	s.mux.HandleFunc("GET /expenses/{id}", s.getExpense)
+	s.mux.HandleFunc("PATCH /expenses/{id}", s.updateExpense)
+	s.mux.HandleFunc("DELETE /expenses/{id}", s.deleteExpense)
+	s.mux.HandleFunc("GET /summary", s.summary)
+
+	web, _ := fs.Sub(webFiles, "web")
+	s.mux.Handle("GET /", http.FileServer(http.FS(web)))
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+type addExpenseRequest struct {
+	Description string `json:"description"`
+	Amount      uint   `json:"amount"`
+}
+
+type updateExpenseRequest struct {
+	Description *string `json:"description"`
+	Amount      *uint   `json:"amount"`
+}
+
+type summaryResponse struct {
+	Total  uint  `json:"total"`
+	Income uint  `json:"income"`
+	Net    int64 `json:"net"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// listExpenses returns all records, optional from and to query parameters (YYYY-MM-DD) limit the period.
+func (s *Server) listExpenses(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	period, err := DateRangePeriod(query.Get("from"), query.Get("to"), s.tracker.Location())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	records := s.tracker.GetByPeriod(period)
+
+	items := make([]jsonRecord, 0, len(records))
+	for _, record := range records {
+		items = append(items, toJsonRecord(record))
+	}
+	writeJson(w, http.StatusOK, items)
+}
+
+func (s *Server) addExpense(w http.ResponseWriter, r *http.Request) {
+	var request addExpenseRequest
+	if err := readJson(w, r, &request); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+
+	if request.Amount == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("invalid amount"))
+		return
+	}
+	if request.Description == "" {
+		writeError(w, http.StatusBadRequest, errors.New("invalid description"))
+		return
+	}
+
+	record, err := s.tracker.Add(request.Description, request.Amount)
+	if err != nil {
+		writeTrackerError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", "/expenses/"+strconv.FormatUint(uint64(record.Id), 10))
+	writeJson(w, http.StatusCreated, toJsonRecord(record))
+}
+
+func (s *Server) getExpense(w http.ResponseWriter, r *http.Request) {
+	id, err := s.pathId(r)
+	if err != nil {
+		writeTrackerError(w, err)
+		return
+	}
+
+	record, err := s.tracker.Get(id)
+	if err != nil {
+		writeTrackerError(w, err)
+		return
+	}
+
+	writeJson(w, http.StatusOK, toJsonRecord(record))
+}
+
+func (s *Server) updateExpense(w http.ResponseWriter, r *http.Request) {
+	id, err := s.pathId(r)
+	if err != nil {
+		writeTrackerError(w, err)
+		return
+	}
+
+	var request updateExpenseRequest
+	if err := readJson(w, r, &request); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+
+	if request.Description == nil && request.Amount == nil {
+		writeError(w, http.StatusBadRequest, errors.New("required description or amount"))
+		return
+	}
+	description := ""
+	if request.Description != nil {
+		if *request.Description == "" {
+			writeError(w, http.StatusBadRequest, errors.New("invalid description"))
+			return
+		}
+		description = *request.Description
+	}
+	var amount uint = DoNotUpdateAmount
+	if request.Amount != nil {
+		if *request.Amount == 0 {
+			writeError(w, http.StatusBadRequest, errors.New("invalid amount"))
+			return
+		}
+		amount = *request.Amount
+	}
+
+	record, err := s.tracker.Update(id, description, amount)
+	if err != nil {
+		writeTrackerError(w, err)
+		return
+	}
+
+	writeJson(w, http.StatusOK, toJsonRecord(record))
+}
+
+func (s *Server) deleteExpense(w http.ResponseWriter, r *http.Request) {
+	id, err := s.pathId(r)
+	if err != nil {
+		writeTrackerError(w, err)
+		return
+	}
+
+	deleted, err := s.tracker.DeleteRecord(id)
+	if err == nil && !deleted {
+		err = recordNotFound
+	}
+	if err != nil {
+		writeTrackerError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// summary mirrors the summary command: all time total without parameters,
+// year total with year only, month total with month and optional year.
+func (s *Server) summary(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	loc := s.tracker.Location()
+	now := s.tracker.Now().In(loc)
+
+	year := now.Year()
+	if query.Has("year") {
+		value, err := strconv.Atoi(query.Get("year"))
+		if err != nil || value < 1970 || value > 9999 {
+			writeError(w, http.StatusBadRequest, errors.New("invalid year"))
+			return
+		}
+		year = value
+	}
+	month := now.Month()
+	if query.Has("month") {
+		value, err := strconv.Atoi(query.Get("month"))
+		if err != nil || value < 1 || value > 12 {
+			writeError(w, http.StatusBadRequest, errors.New("invalid month"))
+			return
+		}
+		month = time.Month(value)
+	}
+
+	var balance Balance
+	switch {
+	case query.Has("month"):
+		balance = ComputeBalance(s.tracker.GetByPeriod(MonthPeriod(month, year, loc)))
+	case query.Has("year"):
+		balance = ComputeBalance(s.tracker.GetByPeriod(YearPeriod(year, loc)))
+	default:
+		balance = ComputeBalance(s.tracker.GetAll())
+	}
+	writeJson(w, http.StatusOK, summaryResponse{Total: balance.Expenses, Income: balance.Income, Net: balance.Net()})
+}
+
+// pathId resolves the record ID or ULID in the request path.
+func (s *Server) pathId(r *http.Request) (RecordId, error) {
+	return s.tracker.ResolveId(r.PathValue("id"))
+}
+
+func readJson(w http.ResponseWriter, r *http.Request, value any) error {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		return unsupportedMediaType
+	}
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(value); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	return nil
+}
+
+func writeJson(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJson(w, status, errorResponse{Error: err.Error()})
+}
+
+// writeRequestError responds to a request body readJson refused.
+func writeRequestError(w http.ResponseWriter, err error) {
+	if errors.Is(err, unsupportedMediaType) {
+		writeError(w, http.StatusUnsupportedMediaType, err)
+		return
+	}
+	writeError(w, http.StatusBadRequest, err)
+}
+
+func writeTrackerError(w http.ResponseWriter, err error) {
+	if errors.Is(err, recordNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if errors.Is(err, invalidRecordId) {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if errors.Is(err, readOnlyLedger) {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeError(w, http.StatusInternalServerError, err)
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestServer(t *testing.T, storage *FakeStorage) *httptest.Server {
+	t.Helper()
+	tracker, err := NewTracker(storage)
+	if err != nil {
+		t.Fatalf("NewTracker() error = %v", err)
+	}
+	server := httptest.NewServer(NewServer(tracker))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func doRequest(t *testing.T, server *httptest.Server, method, path, body string) (*http.Response, string) {
+	t.Helper()
+	request, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("NewRequest() error = %v", err)
+	}
+	if body != "" {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	response, err := server.Client().Do(request)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	defer response.Body.Close()
+
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	return response, string(content)
+}
+
+func TestServer(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		body         string
+		saveErr      error
+		wantStatus   int
+		wantContains string
+	}{
+		{name: "ListAll", method: "GET", path: "/expenses", wantStatus: http.StatusOK, wantContains: `"description":"Dinner"`},
+		{name: "ListPeriod", method: "GET", path: "/expenses?from=2024-02-01", wantStatus: http.StatusOK, wantContains: `[{"id":2,`},
+		{name: "ListInvalidPeriod", method: "GET", path: "/expenses?from=yesterday", wantStatus: http.StatusBadRequest, wantContains: "invalid date"},
+		{name: "Get", method: "GET", path: "/expenses/1", wantStatus: http.StatusOK, wantContains: `"amount":20`},
+		{name: "GetNotFound", method: "GET", path: "/expenses/42", wantStatus: http.StatusNotFound, wantContains: "record not found"},
+		{name: "GetInvalidId", method: "GET", path: "/expenses/abc", wantStatus: http.StatusBadRequest, wantContains: "invalid ID"},
+		{name: "Add", method: "POST", path: "/expenses", body: `{"description":"Coffee","amount":5}`, wantStatus: http.StatusCreated, wantContains: `"id":3`},
+		{name: "AddZeroAmount", method: "POST", path: "/expenses", body: `{"description":"Coffee"}`, wantStatus: http.StatusBadRequest, wantContains: "invalid amount"},
+		{name: "AddEmptyDescription", method: "POST", path: "/expenses", body: `{"amount":5}`, wantStatus: http.StatusBadRequest, wantContains: "invalid description"},
+		{name: "AddMalformedBody", method: "POST", path: "/expenses", body: `{"amount":`, wantStatus: http.StatusBadRequest, wantContains: "invalid request body"},
+		{name: "AddUnknownField", method: "POST", path: "/expenses", body: `{"description":"Coffee","amount":5,"price":5}`, wantStatus: http.StatusBadRequest, wantContains: "invalid request body"},
+		{name: "AddStorageError", method: "POST", path: "/expenses", body: `{"description":"Coffee","amount":5}`, saveErr: errors.New("disk full"), wantStatus: http.StatusInternalServerError, wantContains: "disk full"},
+		{name: "AddReadOnlyLedger", method: "POST", path: "/expenses", body: `{"description":"Coffee","amount":5}`, saveErr: readOnlyLedger, wantStatus: http.StatusConflict, wantContains: "doctor --repair"},
+		{name: "UpdateAmount", method: "PATCH", path: "/expenses/1", body: `{"amount":25}`, wantStatus: http.StatusOK, wantContains: `"amount":25,"description":"Lunch"`},
+		{name: "UpdateNothing", method: "PATCH", path: "/expenses/1", body: `{}`, wantStatus: http.StatusBadRequest, wantContains: "required description or amount"},
+		{name: "UpdateEmptyDescription", method: "PATCH", path: "/expenses/1", body: `{"description":""}`, wantStatus: http.StatusBadRequest, wantContains: "invalid description"},
+		{name: "UpdateNotFound", method: "PATCH", path: "/expenses/42", body: `{"amount":25}`, wantStatus: http.StatusNotFound, wantContains: "record not found"},
+		{name: "Delete", method: "DELETE", path: "/expenses/2", wantStatus: http.StatusNoContent},
+		{name: "DeleteNotFound", method: "DELETE", path: "/expenses/42", wantStatus: http.StatusNotFound, wantContains: "record not found"},
+		{name: "MethodNotAllowed", method: "PUT", path: "/expenses/1", wantStatus: http.StatusMethodNotAllowed},
+		{name: "SummaryAll", method: "GET", path: "/summary", wantStatus: http.StatusOK, wantContains: `{"total":50,"income":0,"net":-50}`},
+		{name: "SummaryYear", method: "GET", path: "/summary?year=2024", wantStatus: http.StatusOK, wantContains: `{"total":50,"income":0,"net":-50}`},
+		{name: "SummaryMonth", method: "GET", path: "/summary?month=2&year=2024", wantStatus: http.StatusOK, wantContains: `{"total":30,"income":0,"net":-30}`},
+		{name: "WebIndex", method: "GET", path: "/", wantStatus: http.StatusOK, wantContains: "<title>Expense Tracker</title>"},
+		{name: "WebScript", method: "GET", path: "/app.js", wantStatus: http.StatusOK, wantContains: "/expenses"},
+		{name: "WebNotFound", method: "GET", path: "/missing.js", wantStatus: http.StatusNotFound},
+		{name: "SummaryInvalidMonth", method: "GET", path: "/summary?month=13", wantStatus: http.StatusBadRequest, wantContains: "invalid month"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t, &FakeStorage{records: testRecords()[:2], saveError: tt.saveErr})
+
+			response, body := doRequest(t, server, tt.method, tt.path, tt.body)
+
+			if response.StatusCode != tt.wantStatus {
+				t.Errorf("%s %s status = %d, want %d, body %s", tt.method, tt.path, response.StatusCode, tt.wantStatus, body)
+			}
+			if !strings.Contains(body, tt.wantContains) {
+				t.Errorf("%s %s body = %s, want to contain %s", tt.method, tt.path, body, tt.wantContains)
+			}
+		})
+	}
+}
+
+func TestServerAddPersistsRecord(t *testing.T) {
+	storage := &FakeStorage{records: testRecords()[:2]}
+	server := newTestServer(t, storage)
+
+	response, body := doRequest(t, server, "POST", "/expenses", `{"description":"Coffee","amount":5}`)
+	if response.StatusCode != http.StatusCreated {
+		t.Fatalf("POST /expenses status = %d, body %s", response.StatusCode, body)
+	}
+	if location := response.Header.Get("Location"); location != "/expenses/3" {
+		t.Errorf("POST /expenses Location = %s, want /expenses/3", location)
+	}
+
+	var created jsonRecord
+	if err := json.Unmarshal([]byte(body), &created); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(storage.records) != 3 || storage.records[2].Description != "Coffee" || created.Id != storage.records[2].Id {
+		t.Errorf("storage records = %v, created %v", storage.records, created)
+	}
+
+	_, body = doRequest(t, server, "GET", "/expenses/3", "")
+	if !strings.Contains(body, `"description":"Coffee"`) {
+		t.Errorf("GET /expenses/3 body = %s", body)
+	}
+
+	response, body = doRequest(t, server, "GET", "/expenses/"+created.Uid, "")
+	if response.StatusCode != http.StatusOK || !strings.Contains(body, `"description":"Coffee"`) {
+		t.Errorf("GET /expenses/%s status = %d, body %s", created.Uid, response.StatusCode, body)
+	}
+}
+
+func TestServerSummaryIncome(t *testing.T) {
+	records := append(testRecords()[:2],
+		TrackerRecord{Id: 3, Description: "Salary", Amount: 1000, CreatedAt: time.Date(2024, 2, 1, 9, 0, 0, 0, time.Local), Kind: KindIncome},
+		TrackerRecord{Id: 4, Description: "Withdrawal", Amount: 100, CreatedAt: time.Date(2024, 2, 2, 9, 0, 0, 0, time.Local), Kind: KindTransferOut},
+		TrackerRecord{Id: 5, Description: "Withdrawal", Amount: 100, CreatedAt: time.Date(2024, 2, 2, 9, 0, 0, 0, time.Local), Kind: KindTransferIn, Account: "cash"},
+	)
+	server := newTestServer(t, &FakeStorage{records: records})
+
+	response, body := doRequest(t, server, "GET", "/summary?month=2&year=2024", "")
+	if response.StatusCode != http.StatusOK || !strings.Contains(body, `{"total":30,"income":1000,"net":970}`) {
+		t.Errorf("GET /summary = %d %s, want income and net without transfers", response.StatusCode, body)
+	}
+}
+
+func TestServerRequiresJsonBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		wantStatus  int
+	}{
+		{name: "AddForm", method: "POST", path: "/expenses", contentType: "application/x-www-form-urlencoded", wantStatus: http.StatusUnsupportedMediaType},
+		{name: "AddPlainText", method: "POST", path: "/expenses", contentType: "text/plain", wantStatus: http.StatusUnsupportedMediaType},
+		{name: "AddMissing", method: "POST", path: "/expenses", wantStatus: http.StatusUnsupportedMediaType},
+		{name: "AddCharset", method: "POST", path: "/expenses", contentType: "application/json; charset=utf-8", wantStatus: http.StatusCreated},
+		{name: "UpdatePlainText", method: "PATCH", path: "/expenses/1", contentType: "text/plain", wantStatus: http.StatusUnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &FakeStorage{records: testRecords()[:2]}
+			server := newTestServer(t, storage)
+
+			request, err := http.NewRequest(tt.method, server.URL+tt.path, strings.NewReader(`{"description":"Coffee","amount":5}`))
+			if err != nil {
+				t.Fatalf("NewRequest() error = %v", err)
+			}
+			if tt.contentType != "" {
+				request.Header.Set("Content-Type", tt.contentType)
+			}
+			response, err := server.Client().Do(request)
+			if err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			response.Body.Close()
+
+			if response.StatusCode != tt.wantStatus {
+				t.Errorf("%s %s status = %d, want %d", tt.method, tt.path, response.StatusCode, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusUnsupportedMediaType && (len(storage.records) != 2 || storage.records[0].Description != "Lunch") {
+				t.Errorf("records changed by refused request: %v", storage.records)
+			}
+		})
+	}
+}
+
+func TestServerConcurrentDelete(t *testing.T) {
+	server := newTestServer(t, &FakeStorage{records: testRecords()[:2]})
+
+	statuses := make([]int, 8)
+	var wg sync.WaitGroup
+	for i := range statuses {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			request, err := http.NewRequest("DELETE", server.URL+"/expenses/2", nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			response, err := server.Client().Do(request)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			response.Body.Close()
+			statuses[i] = response.StatusCode
+		}()
+	}
+	wg.Wait()
+
+	deleted := 0
+	for _, status := range statuses {
+		switch status {
+		case http.StatusNoContent:
+			deleted++
+		case http.StatusNotFound:
+		default:
+			t.Errorf("DELETE status = %d, want 204 or 404", status)
+		}
+	}
+	if deleted != 1 {
+		t.Errorf("DELETE returned 204 %d times, want once", deleted)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+const shellPrompt = "expense-tracker> "
+
+var shellBuiltins = []string{"exit", "history", "quit"}
+
+func ShellCmd(_ *flag.FlagSet, env *Env) func(tracker *Tracker) error {
+	return func(tracker *Tracker) error {
+		return runShell(env, tracker)
+	}
+}
+
+func runShell(env *Env, tracker *Tracker) error {
+	editor := newLineEditor(env.Stdin, env.Stdout, func(before string) []string {
+		return completeShellLine(before, env)
+	})
+	terminal, interactive := terminalFile(env.Stdin)
+
+	for {
+		line, err := readShellLine(editor, terminal, interactive)
+		if errors.Is(err, errInterrupted) {
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		words, err := splitArgs(line)
+		if err != nil {
+			fmt.Fprintf(env.Stderr, "error: %v\n", err)
+			continue
+		}
+		if len(words) == 0 {
+			continue
+		}
+
+		switch words[0] {
+		case "exit", "quit":
+			return nil
+		case "history":
+			for i, entry := range editor.History() {
+				fmt.Fprintf(env.Stdout, "%4d  %s\n", i+1, entry)
+			}
+			continue
+		case "shell":
+			fmt.Fprintln(env.Stderr, "error: already in shell")
+			continue
+		}
+
+		err = runCommand(words, env, tracker)
+		fmt.Fprintln(env.Stdout)
+		if err != nil && !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(env.Stderr, "error: %v\n", err)
+		}
+
+		// records in memory are stale after the ledger file is replaced, saving them would overwrite it
+		if command, ok := findCommand(words[0]); ok && command.ReplacesLedger {
+			tracker, err = openTracker(env)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func readShellLine(editor *lineEditor, terminal *os.File, interactive bool) (string, error) {
+	if interactive {
+		restore, err := enableRawMode(terminal)
+		if err == nil {
+			defer restore()
+			return editor.ReadLine(shellPrompt)
+		}
+	}
+	return editor.ReadPlainLine()
+}
+
+// splitArgs splits a line into words by spaces, single and double quotes group words
+// and backslash escapes the next character outside of single quotes.
+func splitArgs(line string) ([]string, error) {
+	words := make([]string, 0)
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inWord = true
+		case unicode.IsSpace(r):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	if quote != 0 || escaped {
+		return nil, errors.New("unterminated quote or escape")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// completeShellLine returns commands for the first word and flags of the command for the following words.
+func completeShellLine(before string, env *Env) []string {
+	wordStart := strings.LastIndexFunc(before, unicode.IsSpace) + 1
+	word := before[wordStart:]
+	previous := strings.Fields(before[:wordStart])
+
+	var options []string
+	if len(previous) == 0 {
+		options = append(options, shellBuiltins...)
+		for _, command := range visibleCommands(Commands) {
+			options = append(options, command.Name)
+		}
+	} else if command, ok := findCommand(previous[0]); ok {
+		options = append(options, command.Args...)
+		if strings.HasPrefix(word, "-") {
+			options = command.FlagNames(env)
+		}
+	}
+
+	candidates := make([]string, 0)
+	for _, option := range options {
+		if strings.HasPrefix(option, word) && !slices.Contains(previous, option) {
+			candidates = append(candidates, option)
+		}
+	}
+	slices.Sort(candidates)
+	return candidates
+}
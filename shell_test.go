@@ -0,0 +1,60 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    []string
+		wantErr bool
+	}{
+		{name: "Empty", line: "   ", want: []string{}},
+		{name: "Words", line: "add  --amount 10", want: []string{"add", "--amount", "10"}},
+		{name: "DoubleQuotes", line: `add --description "Lunch at cafe"`, want: []string{"add", "--description", "Lunch at cafe"}},
+		{name: "SingleQuotes", line: `add --description='Say "hi"'`, want: []string{"add", `--description=Say "hi"`}},
+		{name: "Escapes", line: `add --description Lunch\ \"cafe\"`, want: []string{"add", "--description", `Lunch "cafe"`}},
+		{name: "EmptyQuoted", line: `add --description ""`, want: []string{"add", "--description", ""}},
+		{name: "UnterminatedQuote", line: `add --description "Lunch`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitArgs(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("splitArgs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompleteShellLine(t *testing.T) {
+	tests := []struct {
+		before string
+		want   []string
+	}{
+		{before: "", want: []string{"accounts", "add", "attach", "attachments", "backup", "chart", "completion", "decrypt", "delete", "doctor", "encrypt", "exit", "export", "help", "history", "list", "payees", "quit", "report", "serve", "shell", "stats", "summary", "tags", "transfer", "tui", "update"}},
+		{before: "s", want: []string{"serve", "shell", "stats", "summary"}},
+		{before: "completion ", want: []string{"bash", "fish", "zsh"}},
+		{before: "upd", want: []string{"update"}},
+		{before: "update --", want: []string{"--account", "--amount", "--description", "--id", "--notes", "--payee", "--tag", "--untag"}},
+		{before: "update --id 1 --", want: []string{"--account", "--amount", "--description", "--notes", "--payee", "--tag", "--untag"}},
+		{before: "summary --m", want: []string{"--month"}},
+		{before: "add Lunch", want: []string{}},
+		{before: "unknown --", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.before, func(t *testing.T) {
+			if got := completeShellLine(tt.before, flagsTestEnv()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("completeShellLine(%q) = %v, want %v", tt.before, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"time"
+)
+
+var StatsPercentiles = []float64{25, 75, 90, 95}
+
+type WeekdayStats struct {
+	Weekday time.Weekday
+	Count   int
+	Total   uint
+}
+
+type Stats struct {
+	Count  int
+	Total  uint
+	Mean   float64
+	Median float64
+	Min    uint
+	Max    uint
+	StdDev float64
+	// values of StatsPercentiles in the same order
+	Percentiles []float64
+	// records with the largest amounts, the largest first
+	Largest []TrackerRecord
+	// weekdays ordered by records count, then by total, the busiest first
+	Weekdays []WeekdayStats
+}
+
+// ComputeStats describes amounts of records, top is the number of largest records to keep.
+func ComputeStats(records []TrackerRecord, top int) Stats {
+	stats := Stats{
+		Count:       len(records),
+		Percentiles: make([]float64, len(StatsPercentiles)),
+		Largest:     make([]TrackerRecord, 0),
+		Weekdays:    make([]WeekdayStats, 7),
+	}
+	for i := range stats.Weekdays {
+		stats.Weekdays[i].Weekday = time.Weekday(i)
+	}
+	if len(records) == 0 {
+		return stats
+	}
+
+	amounts := make([]uint, len(records))
+	for i, record := range records {
+		amounts[i] = record.Amount
+		stats.Total += record.Amount
+
+		weekday := &stats.Weekdays[record.CreatedAt.Weekday()]
+		weekday.Count++
+		weekday.Total += record.Amount
+	}
+	slices.Sort(amounts)
+
+	stats.Min = amounts[0]
+	stats.Max = amounts[len(amounts)-1]
+	stats.Mean = float64(stats.Total) / float64(stats.Count)
+	stats.Median = Percentile(amounts, 50)
+	for i, percent := range StatsPercentiles {
+		stats.Percentiles[i] = Percentile(amounts, percent)
+	}
+
+	var squares float64 = 0
+	for _, amount := range amounts {
+		diff := float64(amount) - stats.Mean
+		squares += diff * diff
+	}
+	stats.StdDev = math.Sqrt(squares / float64(stats.Count))
+
+	largest := slices.Clone(records)
+	slices.SortStableFunc(largest, func(a, b TrackerRecord) int {
+		return cmp.Compare(b.Amount, a.Amount)
+	})
+	stats.Largest = largest[:min(top, len(largest))]
+
+	slices.SortStableFunc(stats.Weekdays, func(a, b WeekdayStats) int {
+		if a.Count != b.Count {
+			return cmp.Compare(b.Count, a.Count)
+		}
+		return cmp.Compare(b.Total, a.Total)
+	})
+
+	return stats
+}
+
+// Percentile returns linearly interpolated percentile (0-100) of sorted values.
+func Percentile(sorted []uint, percent float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := percent / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	fraction := rank - float64(lower)
+	return float64(sorted[lower]) + (float64(sorted[upper])-float64(sorted[lower]))*fraction
+}
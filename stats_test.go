@@ -0,0 +1,83 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestComputeStats(t *testing.T) {
+	// 2024-01-01 is Monday
+	records := []TrackerRecord{
+		{Id: 1, Amount: 10, CreatedAt: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{Id: 2, Amount: 40, CreatedAt: time.Date(2024, 1, 1, 18, 0, 0, 0, time.UTC)},
+		{Id: 3, Amount: 20, CreatedAt: time.Date(2024, 1, 3, 12, 0, 0, 0, time.UTC)},
+		{Id: 4, Amount: 50, CreatedAt: time.Date(2024, 1, 5, 12, 0, 0, 0, time.UTC)},
+		{Id: 5, Amount: 30, CreatedAt: time.Date(2024, 1, 8, 12, 0, 0, 0, time.UTC)},
+	}
+
+	stats := ComputeStats(records, 2)
+
+	if stats.Count != 5 || stats.Total != 150 || stats.Min != 10 || stats.Max != 50 {
+		t.Errorf("ComputeStats() count/total/min/max = %d/%d/%d/%d, want 5/150/10/50", stats.Count, stats.Total, stats.Min, stats.Max)
+	}
+	if stats.Mean != 30 || stats.Median != 30 {
+		t.Errorf("ComputeStats() mean/median = %v/%v, want 30/30", stats.Mean, stats.Median)
+	}
+	if math.Abs(stats.StdDev-math.Sqrt(200)) > 1e-9 {
+		t.Errorf("ComputeStats() std deviation = %v, want %v", stats.StdDev, math.Sqrt(200))
+	}
+	if want := []float64{20, 40, 46, 48}; !reflect.DeepEqual(stats.Percentiles, want) {
+		t.Errorf("ComputeStats() percentiles = %v, want %v", stats.Percentiles, want)
+	}
+
+	largest := []RecordId{}
+	for _, record := range stats.Largest {
+		largest = append(largest, record.Id)
+	}
+	if want := []RecordId{4, 2}; !reflect.DeepEqual(largest, want) {
+		t.Errorf("ComputeStats() largest = %v, want %v", largest, want)
+	}
+
+	busiest := stats.Weekdays[0]
+	if busiest.Weekday != time.Monday || busiest.Count != 3 || busiest.Total != 80 {
+		t.Errorf("ComputeStats() busiest weekday = %+v, want Monday with 3 records and total 80", busiest)
+	}
+	if stats.Weekdays[1].Weekday != time.Friday || stats.Weekdays[2].Weekday != time.Wednesday {
+		t.Errorf("ComputeStats() weekdays order = %v", stats.Weekdays)
+	}
+}
+
+func TestComputeStatsNoRecords(t *testing.T) {
+	stats := ComputeStats(nil, 5)
+	if stats.Count != 0 || stats.Mean != 0 || stats.Median != 0 || len(stats.Largest) != 0 {
+		t.Errorf("ComputeStats() with no records = %+v", stats)
+	}
+	if len(stats.Weekdays) != 7 || len(stats.Percentiles) != len(StatsPercentiles) {
+		t.Errorf("ComputeStats() with no records weekdays = %v, percentiles = %v", stats.Weekdays, stats.Percentiles)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []uint
+		percent float64
+		want    float64
+	}{
+		{name: "Empty", values: nil, percent: 50, want: 0},
+		{name: "Single", values: []uint{7}, percent: 90, want: 7},
+		{name: "MedianOfEven", values: []uint{1, 2, 3, 4}, percent: 50, want: 2.5},
+		{name: "Min", values: []uint{1, 2, 3, 4}, percent: 0, want: 1},
+		{name: "Max", values: []uint{1, 2, 3, 4}, percent: 100, want: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Percentile(tt.values, tt.percent); got != tt.want {
+				t.Errorf("Percentile() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
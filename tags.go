@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+var invalidTag = errors.New("invalid tag")
+
+// normalizeTag lowercases a tag and strips the leading #, tags consist of letters, digits, '-' and '_'.
+func normalizeTag(tag string) (string, error) {
+	normalized := strings.ToLower(strings.TrimPrefix(tag, "#"))
+	if normalized == "" {
+		return "", fmt.Errorf("%w %q", invalidTag, tag)
+	}
+	for _, r := range normalized {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' {
+			return "", fmt.Errorf("%w %q", invalidTag, tag)
+		}
+	}
+	return normalized, nil
+}
+
+// tagSet normalizes tags and returns them sorted without duplicates, nil for no tags.
+func tagSet(tags []string) ([]string, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	set := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		normalized, err := normalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		set = append(set, normalized)
+	}
+	slices.Sort(set)
+	return slices.Compact(set), nil
+}
+
+// formatTags returns tags for display, e.g. "#business #trip-lisbon".
+func formatTags(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	return "#" + strings.Join(tags, " #")
+}
+
+// tagsFlag is a repeatable flag collecting tags, one flag can also hold comma separated tags.
+type tagsFlag []string
+
+func (f *tagsFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return strings.Join(*f, ",")
+}
+
+func (f *tagsFlag) Set(value string) error {
+	for _, tag := range strings.Split(value, ",") {
+		normalized, err := normalizeTag(strings.TrimSpace(tag))
+		if err != nil {
+			return err
+		}
+		*f = append(*f, normalized)
+	}
+	return nil
+}
+
+// TagFilter selects records having all of the All tags and, if Any is set, at least one of the Any tags.
+type TagFilter struct {
+	All []string
+	Any []string
+}
+
+// addTagFilterFlags defines --tag and --any-tag flags on the flag set, action is used in flag descriptions.
+func addTagFilterFlags(flags *flag.FlagSet, action string) *TagFilter {
+	filter := &TagFilter{}
+	flags.Var((*tagsFlag)(&filter.All), "tag", action+" with the `tag`, repeat to require all of the tags")
+	flags.Var((*tagsFlag)(&filter.Any), "any-tag", action+" with any of the tags, repeat for each `tag`")
+	return filter
+}
+
+// IsEmpty reports whether the filter selects all records.
+func (f TagFilter) IsEmpty() bool {
+	return len(f.All) == 0 && len(f.Any) == 0
+}
+
+func (f TagFilter) Matches(record TrackerRecord) bool {
+	for _, tag := range f.All {
+		if !slices.Contains(record.Tags, tag) {
+			return false
+		}
+	}
+	if len(f.Any) == 0 {
+		return true
+	}
+	for _, tag := range f.Any {
+		if slices.Contains(record.Tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter returns records matching the filter.
+func (f TagFilter) Filter(records []TrackerRecord) []TrackerRecord {
+	filtered := make([]TrackerRecord, 0, len(records))
+	for _, record := range records {
+		if f.Matches(record) {
+			filtered = append(filtered, record)
+		}
+	}
+	return filtered
+}
+
+type TagTotal struct {
+	Tag   string
+	Count int
+	Total uint
+}
+
+// ComputeTagTotals counts records and sums amounts per tag, sorted by tag.
+func ComputeTagTotals(records []TrackerRecord) []TagTotal {
+	totals := make(map[string]TagTotal)
+	for _, record := range records {
+		for _, tag := range record.Tags {
+			total := totals[tag]
+			total.Tag = tag
+			total.Count++
+			total.Total += record.Amount
+			totals[tag] = total
+		}
+	}
+
+	result := make([]TagTotal, 0, len(totals))
+	for _, total := range totals {
+		result = append(result, total)
+	}
+	slices.SortFunc(result, func(a, b TagTotal) int {
+		return strings.Compare(a.Tag, b.Tag)
+	})
+	return result
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTagSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		tags    []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "Empty", want: nil},
+		{name: "NormalizedSortedUnique", tags: []string{"#Trip-Lisbon", "business", "BUSINESS", "food_2024"}, want: []string{"business", "food_2024", "trip-lisbon"}},
+		{name: "Unicode", tags: []string{"café"}, want: []string{"café"}},
+		{name: "OnlyHash", tags: []string{"#"}, wantErr: true},
+		{name: "Space", tags: []string{"trip lisbon"}, wantErr: true},
+		{name: "Comma", tags: []string{"a,b"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tagSet(tt.tags)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("tagSet() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, invalidTag) {
+				t.Errorf("tagSet() error = %v, want %v", err, invalidTag)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tagSet() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTagsFlag(t *testing.T) {
+	var tags tagsFlag
+	for _, value := range []string{"business", "#Trip, food"} {
+		if err := tags.Set(value); err != nil {
+			t.Fatalf("Set(%q) error = %v", value, err)
+		}
+	}
+	if want := (tagsFlag{"business", "trip", "food"}); !reflect.DeepEqual(tags, want) {
+		t.Errorf("tags = %v, want %v", tags, want)
+	}
+	if err := tags.Set("a b"); !errors.Is(err, invalidTag) {
+		t.Errorf("Set() error = %v, want %v", err, invalidTag)
+	}
+}
+
+func TestTagFilter(t *testing.T) {
+	records := []TrackerRecord{
+		{Id: 1, Tags: []string{"business", "trip-lisbon"}},
+		{Id: 2, Tags: []string{"business"}},
+		{Id: 3, Tags: []string{"trip-lisbon"}},
+		{Id: 4},
+	}
+
+	tests := []struct {
+		name   string
+		filter TagFilter
+		want   []RecordId
+	}{
+		{name: "Empty", want: []RecordId{1, 2, 3, 4}},
+		{name: "All", filter: TagFilter{All: []string{"business", "trip-lisbon"}}, want: []RecordId{1}},
+		{name: "Any", filter: TagFilter{Any: []string{"business", "trip-lisbon"}}, want: []RecordId{1, 2, 3}},
+		{name: "AllAndAny", filter: TagFilter{All: []string{"business"}, Any: []string{"trip-lisbon", "food"}}, want: []RecordId{1}},
+		{name: "NoMatch", filter: TagFilter{All: []string{"food"}}, want: []RecordId{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]RecordId, 0)
+			for _, record := range tt.filter.Filter(records) {
+				got = append(got, record.Id)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Filter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComputeTagTotals(t *testing.T) {
+	records := []TrackerRecord{
+		{Amount: 10, Tags: []string{"trip-lisbon", "business"}},
+		{Amount: 20, Tags: []string{"business"}},
+		{Amount: 40},
+	}
+	want := []TagTotal{
+		{Tag: "business", Count: 2, Total: 30},
+		{Tag: "trip-lisbon", Count: 1, Total: 10},
+	}
+	if got := ComputeTagTotals(records); !reflect.DeepEqual(got, want) {
+		t.Errorf("ComputeTagTotals() = %v, want %v", got, want)
+	}
+}
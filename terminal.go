@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// enableRawMode switches the terminal to raw mode without echo using stty,
+// the returned function restores the previous mode. Fails where stty is not available.
+func enableRawMode(f *os.File) (restore func() error, err error) {
+	state, err := stty(f, "-g")
+	if err != nil {
+		return nil, err
+	}
+	_, err = stty(f, "raw", "-echo")
+	if err != nil {
+		return nil, err
+	}
+	return func() error {
+		_, err := stty(f, strings.TrimSpace(state))
+		return err
+	}, nil
+}
+
+// terminalSize returns columns and rows of the terminal, 80x24 if it cannot be detected.
+func terminalSize(f *os.File) (width, height int) {
+	output, err := stty(f, "size")
+	if err == nil {
+		_, err = fmt.Sscan(output, &height, &width)
+	}
+	if err != nil || width <= 0 || height <= 0 {
+		return 80, 24
+	}
+	return width, height
+}
+
+func stty(f *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = f
+	output, err := cmd.Output()
+	return string(output), err
+}
+
+// disableEcho turns off echo of typed characters keeping line editing of the terminal,
+// the returned function restores the previous mode.
+func disableEcho(f *os.File) (restore func() error, err error) {
+	state, err := stty(f, "-g")
+	if err != nil {
+		return nil, err
+	}
+	_, err = stty(f, "-echo")
+	if err != nil {
+		return nil, err
+	}
+	return func() error {
+		_, err := stty(f, strings.TrimSpace(state))
+		return err
+	}, nil
+}
@@ -2,7 +2,13 @@ package main
 
 import (
 	"errors"
+	"fmt"
+	"io"
 	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -11,104 +17,490 @@ const (
 	DoNotUpdateAmount = 0
 )
 
+var (
+	recordNotFound  = errors.New("record not found")
+	invalidRecordId = errors.New("invalid ID")
+	noAttachments   = errors.New("attachments are not available")
+	readOnlyLedger  = errors.New("ledger has rows that cannot be loaded, run doctor --repair before changing it")
+)
+
 type RecordId uint
 
 type TrackerRecord struct {
-	Id          RecordId
+	Id RecordId
+	// ULID assigned on creation for external references, empty for records created before UIDs
+	Uid         string
 	Description string
 	Amount      uint
 	CreatedAt   time.Time
+	// sorted normalized tags without duplicates
+	Tags []string
+	// merchant or person paid, optional
+	Payee string
+	// free-form notes, may span multiple lines
+	Notes       string
+	Attachments []Attachment
+	Kind        RecordKind
+	// normalized account name, empty for the default account
+	Account string
+	// UID of the other half of a transfer between accounts
+	Transfer string
+}
+
+// RecordChange lists changes to a record, zero and nil fields are left unchanged.
+type RecordChange struct {
+	Description string
+	Amount      uint
+	AddTags     []string
+	RemoveTags  []string
+	Payee       *string
+	Notes       *string
+	Account     *string
+}
+
+// inLocation returns copies of records with creation time converted to location for display.
+func inLocation(records []TrackerRecord, location *time.Location) []TrackerRecord {
+	converted := make([]TrackerRecord, len(records))
+	for i, record := range records {
+		record.CreatedAt = record.CreatedAt.In(location)
+		converted[i] = record
+	}
+	return converted
+}
+
+// SearchRecords returns records containing the query in description, payee, notes or tags, ignoring case.
+func SearchRecords(records []TrackerRecord, query string) []TrackerRecord {
+	query = strings.ToLower(query)
+	found := make([]TrackerRecord, 0)
+	for _, record := range records {
+		text := strings.Join([]string{record.Description, record.Payee, record.Notes, formatTags(record.Tags)}, "\n")
+		if strings.Contains(strings.ToLower(text), query) {
+			found = append(found, record)
+		}
+	}
+	return found
 }
 
+// Tracker is safe for concurrent use. Records slice is never modified in place:
+// mutations save a modified copy and replace the slice only if storage succeeds,
+// so slices returned to callers stay valid snapshots.
 type Tracker struct {
+	mu      sync.RWMutex
 	storage TrackerStorage
-	records []TrackerRecord
+	clock   Clock
+	// reporting timezone, records keep their original offsets
+	location *time.Location
+	records  []TrackerRecord
+	// rows skipped by a lenient storage, the tracker is read-only while there are any
+	warnings []LedgerIssue
+	// highest ID ever assigned, IDs of deleted records are not reused
+	lastId RecordId
+	// content of attached files, nil when attachments are not available
+	attachments AttachmentStore
 }
 
 func NewTracker(storage TrackerStorage) (*Tracker, error) {
+	return NewTrackerWithClock(storage, time.Now)
+}
+
+// NewTrackerWithClock creates a tracker taking creation time of new records from clock.
+func NewTrackerWithClock(storage TrackerStorage, clock Clock) (*Tracker, error) {
 	records, err := storage.ReadAll()
 	if err != nil {
 		return nil, err
 	}
-	return &Tracker{storage: storage, records: records}, nil
+	tracker := &Tracker{storage: storage, clock: clock, location: time.Local, records: records, lastId: maxRecordId(records)}
+	if idStorage, ok := storage.(IdStorage); ok {
+		tracker.lastId = max(tracker.lastId, idStorage.LastId())
+	}
+	if warningStorage, ok := storage.(WarningStorage); ok {
+		tracker.warnings = warningStorage.Warnings()
+	}
+	return tracker, nil
+}
+
+// Warnings returns rows of the ledger skipped on load, changes are refused while there are any.
+func (t *Tracker) Warnings() []LedgerIssue {
+	return t.warnings
+}
+
+// SetAttachments sets the store files attached to records are kept in.
+func (t *Tracker) SetAttachments(attachments AttachmentStore) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.attachments = attachments
+}
+
+// Now returns the current time of the tracker clock.
+func (t *Tracker) Now() time.Time {
+	return t.clock()
+}
+
+// Location returns the reporting timezone used to split records into days, months and years.
+func (t *Tracker) Location() *time.Location {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.location
+}
+
+// SetLocation sets the reporting timezone, time.Local by default.
+func (t *Tracker) SetLocation(location *time.Location) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.location = location
 }
 
 func (t *Tracker) Add(description string, amount uint) (TrackerRecord, error) {
-	var nextId RecordId = 1
-	if len(t.records) > 0 {
-		nextId = t.records[len(t.records)-1].Id + 1
+	return t.AddRecord(TrackerRecord{Description: description, Amount: amount})
+}
+
+// AddRecord saves a new record with fields of draft, ID, UID and creation time are assigned by the tracker.
+func (t *Tracker) AddRecord(draft TrackerRecord) (TrackerRecord, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.warnings) > 0 {
+		return TrackerRecord{}, readOnlyLedger
 	}
 
-	record := TrackerRecord{
-		Id:          nextId,
-		Description: description,
-		Amount:      amount,
-		CreatedAt:   time.Now(),
+	tags, err := tagSet(draft.Tags)
+	if err != nil {
+		return TrackerRecord{}, err
 	}
-	records := append(t.records, record)
+	account, err := normalizeAccount(draft.Account)
+	if err != nil {
+		return TrackerRecord{}, err
+	}
+	now := t.clock()
+	uid, err := newUlid(now)
+	if err != nil {
+		return TrackerRecord{}, err
+	}
+	record := draft
+	record.Id = t.lastId + 1
+	record.Uid = uid
+	record.CreatedAt = now
+	record.Tags = tags
+	record.Payee = strings.TrimSpace(draft.Payee)
+	record.Account = account
+	// clip capacity to always append into a new array
+	records := append(slices.Clip(t.records), record)
 
-	err := t.storage.Save(records)
+	err = t.storage.Save(records)
 	if err != nil {
 		return TrackerRecord{}, err
 	}
 	t.records = records
+	t.lastId = record.Id
 	return record, nil
 }
 
+// Delete removes the record, deleting a missing record is not an error.
 func (t *Tracker) Delete(id RecordId) error {
-	records := slices.DeleteFunc(t.records, func(record TrackerRecord) bool {
+	_, err := t.DeleteRecord(id)
+	return err
+}
+
+// DeleteRecord removes the record and reports whether it existed, checked under the same lock
+// so of concurrent callers deleting one record only one removes it.
+func (t *Tracker) DeleteRecord(id RecordId) (bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.warnings) > 0 {
+		return false, readOnlyLedger
+	}
+
+	indexFound := slices.IndexFunc(t.records, func(record TrackerRecord) bool {
 		return record.Id == id
 	})
+	// nothing to save, a save would also push a real backup out of retention
+	if indexFound == -1 {
+		return false, nil
+	}
+	// both halves of a transfer are deleted together
+	transfer := t.records[indexFound].Transfer
+	records := slices.DeleteFunc(slices.Clone(t.records), func(record TrackerRecord) bool {
+		return record.Id == id || (transfer != "" && record.Uid == transfer)
+	})
 
 	err := t.storage.Save(records)
 	if err != nil {
-		return err
+		return false, err
 	}
 	t.records = records
 
-	return nil
+	return true, nil
+}
+
+// Transfer moves amount from one account to another as two linked records,
+// transfers are not counted as income or expenses.
+func (t *Tracker) Transfer(from, to string, amount uint, description string) (TrackerRecord, TrackerRecord, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.warnings) > 0 {
+		return TrackerRecord{}, TrackerRecord{}, readOnlyLedger
+	}
+
+	fromAccount, err := normalizeAccount(from)
+	if err != nil {
+		return TrackerRecord{}, TrackerRecord{}, err
+	}
+	toAccount, err := normalizeAccount(to)
+	if err != nil {
+		return TrackerRecord{}, TrackerRecord{}, err
+	}
+	if fromAccount == toAccount {
+		return TrackerRecord{}, TrackerRecord{}, invalidTransfer
+	}
+
+	now := t.clock()
+	out := TrackerRecord{Id: t.lastId + 1, Description: description, Amount: amount, CreatedAt: now, Kind: KindTransferOut, Account: fromAccount}
+	in := TrackerRecord{Id: t.lastId + 2, Description: description, Amount: amount, CreatedAt: now, Kind: KindTransferIn, Account: toAccount}
+	out.Uid, err = newUlid(now)
+	if err != nil {
+		return TrackerRecord{}, TrackerRecord{}, err
+	}
+	in.Uid, err = newUlid(now)
+	if err != nil {
+		return TrackerRecord{}, TrackerRecord{}, err
+	}
+	out.Transfer = in.Uid
+	in.Transfer = out.Uid
+	records := append(slices.Clip(t.records), out, in)
+
+	err = t.storage.Save(records)
+	if err != nil {
+		return TrackerRecord{}, TrackerRecord{}, err
+	}
+	t.records = records
+	t.lastId = in.Id
+	return out, in, nil
+}
+
+// Attach stores content in the attachment store and links it to the record under name,
+// attaching the same content under the same name again changes nothing.
+func (t *Tracker) Attach(id RecordId, name string, content io.Reader) (TrackerRecord, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.warnings) > 0 {
+		return TrackerRecord{}, readOnlyLedger
+	}
+	if t.attachments == nil {
+		return TrackerRecord{}, noAttachments
+	}
+	if !validAttachmentName(name) {
+		return TrackerRecord{}, fmt.Errorf("%w %q", invalidAttachment, name)
+	}
+
+	indexFound := slices.IndexFunc(t.records, func(record TrackerRecord) bool {
+		return record.Id == id
+	})
+	if indexFound == -1 {
+		return TrackerRecord{}, recordNotFound
+	}
+
+	// content stored for a record that fails to save is removed by the next PruneAttachments
+	key, err := t.attachments.Put(content)
+	if err != nil {
+		return TrackerRecord{}, err
+	}
+	updatedRecord := t.records[indexFound]
+	attachment := Attachment{Name: name, Key: key}
+	if slices.Contains(updatedRecord.Attachments, attachment) {
+		return updatedRecord, nil
+	}
+	updatedRecord.Attachments = append(slices.Clip(updatedRecord.Attachments), attachment)
+	records := slices.Clone(t.records)
+	records[indexFound] = updatedRecord
+
+	err = t.storage.Save(records)
+	if err != nil {
+		return TrackerRecord{}, err
+	}
+	t.records = records
+	return updatedRecord, nil
+}
+
+// OpenAttachment returns content of an attached file by its key.
+func (t *Tracker) OpenAttachment(key string) (io.ReadCloser, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.attachments == nil {
+		return nil, noAttachments
+	}
+	return t.attachments.Open(key)
+}
+
+// PruneAttachments removes stored content no record links to and keep does not report as still needed,
+// e.g. by a backup of the ledger. It returns keys of the removed content.
+func (t *Tracker) PruneAttachments(keep func(key string) bool) ([]string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// rows skipped on load may link to files
+	if len(t.warnings) > 0 {
+		return nil, readOnlyLedger
+	}
+	if t.attachments == nil {
+		return nil, noAttachments
+	}
+	keys, err := t.attachments.Keys()
+	if err != nil {
+		return nil, err
+	}
+
+	linked := make(map[string]bool)
+	for _, record := range t.records {
+		for _, attachment := range record.Attachments {
+			linked[attachment.Key] = true
+		}
+	}
+	removed := make([]string, 0)
+	for _, key := range keys {
+		if linked[key] || keep(key) {
+			continue
+		}
+		err := t.attachments.Delete(key)
+		if err != nil {
+			return removed, err
+		}
+		removed = append(removed, key)
+	}
+	return removed, nil
 }
 
 func (t *Tracker) Update(id RecordId, description string, amount uint) (TrackerRecord, error) {
+	return t.UpdateRecord(id, RecordChange{Description: description, Amount: amount})
+}
+
+// UpdateRecord applies the change to the record and saves it.
+func (t *Tracker) UpdateRecord(id RecordId, change RecordChange) (TrackerRecord, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.warnings) > 0 {
+		return TrackerRecord{}, readOnlyLedger
+	}
+
 	indexFound := slices.IndexFunc(t.records, func(record TrackerRecord) bool {
 		return record.Id == id
 	})
 	if indexFound == -1 {
-		return TrackerRecord{}, errors.New("record not found")
+		return TrackerRecord{}, recordNotFound
 	}
 
 	updatedRecord := t.records[indexFound]
-	if len(description) > 0 {
-		updatedRecord.Description = description
+	if len(change.Description) > 0 {
+		updatedRecord.Description = change.Description
+	}
+	if change.Amount != DoNotUpdateAmount {
+		updatedRecord.Amount = change.Amount
+	}
+	if len(change.AddTags) > 0 || len(change.RemoveTags) > 0 {
+		remove, err := tagSet(change.RemoveTags)
+		if err != nil {
+			return TrackerRecord{}, err
+		}
+		tags := slices.DeleteFunc(slices.Clone(updatedRecord.Tags), func(tag string) bool {
+			return slices.Contains(remove, tag)
+		})
+		updatedRecord.Tags, err = tagSet(append(tags, change.AddTags...))
+		if err != nil {
+			return TrackerRecord{}, err
+		}
 	}
-	if amount != DoNotUpdateAmount {
-		updatedRecord.Amount = amount
+	if change.Payee != nil {
+		updatedRecord.Payee = strings.TrimSpace(*change.Payee)
+	}
+	if change.Notes != nil {
+		updatedRecord.Notes = *change.Notes
+	}
+	if change.Account != nil {
+		account, err := normalizeAccount(*change.Account)
+		if err != nil {
+			return TrackerRecord{}, err
+		}
+		updatedRecord.Account = account
+	}
+	records := slices.Clone(t.records)
+	records[indexFound] = updatedRecord
+	// both halves of a transfer move the same amount
+	if updatedRecord.Transfer != "" {
+		for i := range records {
+			if records[i].Uid == updatedRecord.Transfer {
+				records[i].Amount = updatedRecord.Amount
+			}
+		}
 	}
-	t.records[indexFound] = updatedRecord
 
-	err := t.storage.Save(t.records)
+	err := t.storage.Save(records)
 	if err != nil {
 		return TrackerRecord{}, err
 	}
+	t.records = records
 	return updatedRecord, nil
 }
 
+func (t *Tracker) Get(id RecordId) (TrackerRecord, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	indexFound := slices.IndexFunc(t.records, func(record TrackerRecord) bool {
+		return record.Id == id
+	})
+	if indexFound == -1 {
+		return TrackerRecord{}, recordNotFound
+	}
+	return t.records[indexFound], nil
+}
+
+// ResolveId returns the ID of a record referenced by its numeric ID or its ULID,
+// numeric IDs are returned as is without checking that the record exists.
+func (t *Tracker) ResolveId(ref string) (RecordId, error) {
+	id, err := strconv.ParseUint(ref, 10, 32)
+	if err == nil && id != InvalidId {
+		return RecordId(id), nil
+	}
+	if !isUlid(ref) {
+		return InvalidId, invalidRecordId
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for _, record := range t.records {
+		if record.Uid == ref {
+			return record.Id, nil
+		}
+	}
+	return InvalidId, recordNotFound
+}
+
+// GetAll returns a snapshot of records, it must not be modified.
 func (t *Tracker) GetAll() []TrackerRecord {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
 	return t.records
 }
 
 func (t *Tracker) GetSummary() uint {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
 	var sum uint = 0
 	for _, record := range t.records {
-		sum += record.Amount
+		if record.Kind == KindExpense {
+			sum += record.Amount
+		}
 	}
 	return sum
 }
 
 func (t *Tracker) GetSummaryByMonth(month time.Month, year int) uint {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
 	var sum uint = 0
 	for _, record := range t.records {
-		if record.CreatedAt.Year() == year && record.CreatedAt.Month() == month {
+		createdAt := record.CreatedAt.In(t.location)
+		if record.Kind == KindExpense && createdAt.Year() == year && createdAt.Month() == month {
 			sum += record.Amount
 		}
 	}
@@ -116,11 +508,78 @@ func (t *Tracker) GetSummaryByMonth(month time.Month, year int) uint {
 }
 
 func (t *Tracker) GetSummaryByYear(year int) uint {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var sum uint = 0
+	for _, record := range t.records {
+		if record.Kind == KindExpense && record.CreatedAt.In(t.location).Year() == year {
+			sum += record.Amount
+		}
+	}
+	return sum
+}
+
+func (t *Tracker) GetByPeriod(period Period) []TrackerRecord {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	records := make([]TrackerRecord, 0)
+	for _, record := range t.records {
+		if period.Contains(record.CreatedAt) {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+func (t *Tracker) GetSummaryByPeriod(period Period) uint {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
 	var sum uint = 0
 	for _, record := range t.records {
-		if record.CreatedAt.Year() == year {
+		if record.Kind == KindExpense && period.Contains(record.CreatedAt) {
 			sum += record.Amount
 		}
 	}
 	return sum
 }
+
+type PeriodTotal struct {
+	Period Period
+	Total  uint
+	Count  int
+}
+
+func (p PeriodTotal) Average() float64 {
+	if p.Count == 0 {
+		return 0
+	}
+	return float64(p.Total) / float64(p.Count)
+}
+
+// GetBreakdown splits a bounded period into days, weeks or months and sums expenses of each part in a single pass.
+func (t *Tracker) GetBreakdown(period Period, by Granularity) []PeriodTotal {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	parts := period.Split(by)
+	totals := make([]PeriodTotal, len(parts))
+	for i, part := range parts {
+		totals[i].Period = part
+	}
+
+	for _, record := range t.records {
+		if record.Kind != KindExpense || !period.Contains(record.CreatedAt) {
+			continue
+		}
+		// first part starting after the record, the record belongs to the previous one
+		i := sort.Search(len(parts), func(i int) bool {
+			return parts[i].From.After(record.CreatedAt)
+		})
+		totals[i-1].Total += record.Amount
+		totals[i-1].Count++
+	}
+	return totals
+}
@@ -2,7 +2,11 @@ package main
 
 import (
 	"errors"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -25,6 +29,15 @@ func (f *FakeStorage) Save(records []TrackerRecord) error {
 	return f.saveError
 }
 
+// testRecords returns three expenses from January to March 2024 in local time.
+func testRecords() []TrackerRecord {
+	return []TrackerRecord{
+		{Id: 1, Description: "Lunch", Amount: 20, CreatedAt: time.Date(2024, 1, 15, 12, 0, 0, 0, time.Local)},
+		{Id: 2, Description: "Dinner", Amount: 30, CreatedAt: time.Date(2024, 2, 15, 19, 0, 0, 0, time.Local)},
+		{Id: 3, Description: "Coffee", Amount: 5, CreatedAt: time.Date(2024, 3, 14, 9, 0, 0, 0, time.Local)},
+	}
+}
+
 func TestNewTracker(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -71,6 +84,303 @@ func TestNewTracker(t *testing.T) {
 	}
 }
 
+func TestTrackerReadOnlyWithWarnings(t *testing.T) {
+	storage := NewStorageFromFile(filepath.Join(t.TempDir(), "expenses.csv"))
+	storage.SetLenient(true)
+	content := "1,2024-01-01T01:01:01Z,100,record1\n2,yesterday,200,record2\n"
+	if err := os.WriteFile(storage.filename, []byte(content), 0666); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tracker, err := NewTracker(storage)
+	if err != nil {
+		t.Fatalf("NewTracker() error = %v", err)
+	}
+	if len(tracker.GetAll()) != 1 || len(tracker.Warnings()) != 1 || tracker.Warnings()[0].Line != 2 {
+		t.Fatalf("NewTracker() records = %v, warnings = %v, want 1 record and a warning on line 2", tracker.GetAll(), tracker.Warnings())
+	}
+
+	if _, err := tracker.Add("record3", 300); !errors.Is(err, readOnlyLedger) {
+		t.Errorf("Add() error = %v, want %v", err, readOnlyLedger)
+	}
+	if _, err := tracker.Update(1, "changed", 0); !errors.Is(err, readOnlyLedger) {
+		t.Errorf("Update() error = %v, want %v", err, readOnlyLedger)
+	}
+	if err := tracker.Delete(1); !errors.Is(err, readOnlyLedger) {
+		t.Errorf("Delete() error = %v, want %v", err, readOnlyLedger)
+	}
+	tracker.SetAttachments(NewDirAttachmentStore(t.TempDir()))
+	if _, err := tracker.PruneAttachments(func(string) bool { return false }); !errors.Is(err, readOnlyLedger) {
+		t.Errorf("PruneAttachments() error = %v, want %v", err, readOnlyLedger)
+	}
+	data, _ := os.ReadFile(storage.filename)
+	if string(data) != content {
+		t.Errorf("ledger file changed to %q", data)
+	}
+}
+
+func TestTrackerIdsNotReused(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "expenses.csv")
+	tracker, err := NewTracker(NewStorageFromFile(filename))
+	if err != nil {
+		t.Fatalf("NewTracker() error = %v", err)
+	}
+	for _, description := range []string{"first", "second", "third"} {
+		if _, err := tracker.Add(description, 10); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	if err := tracker.Delete(3); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := tracker.Delete(2); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	record, err := tracker.Add("fourth", 10)
+	if err != nil || record.Id != 4 {
+		t.Errorf("Add() after delete = %v, %v, want ID 4", record, err)
+	}
+	if err := tracker.Delete(4); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	reopened, err := NewTracker(NewStorageFromFile(filename))
+	if err != nil {
+		t.Fatalf("NewTracker() error = %v", err)
+	}
+	record, err = reopened.Add("fifth", 10)
+	if err != nil || record.Id != 5 {
+		t.Errorf("Add() after reopen = %v, %v, want ID 5", record, err)
+	}
+	if !isUlid(record.Uid) {
+		t.Errorf("Add() UID = %q, want ULID", record.Uid)
+	}
+}
+
+func TestTrackerResolveId(t *testing.T) {
+	storage := &FakeStorage{records: []TrackerRecord{
+		{Id: 1, Description: "legacy", Amount: 10},
+		{Id: 2, Uid: "01ARYZ6S41TSV4RRFFQ69G5FAV", Description: "with UID", Amount: 20},
+	}}
+	tracker, err := NewTracker(storage)
+	if err != nil {
+		t.Fatalf("NewTracker() error = %v", err)
+	}
+
+	tests := []struct {
+		ref     string
+		want    RecordId
+		wantErr error
+	}{
+		{ref: "1", want: 1},
+		{ref: "42", want: 42},
+		{ref: "01ARYZ6S41TSV4RRFFQ69G5FAV", want: 2},
+		{ref: "01ARYZ6S41TSV4RRFFQ69G5FAW", wantErr: recordNotFound},
+		{ref: "0", wantErr: invalidRecordId},
+		{ref: "abc", wantErr: invalidRecordId},
+		{ref: "", wantErr: invalidRecordId},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := tracker.ResolveId(tt.ref)
+			if got != tt.want || !errors.Is(err, tt.wantErr) {
+				t.Errorf("ResolveId(%q) = %d, %v, want %d, %v", tt.ref, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTrackerTags(t *testing.T) {
+	tracker, err := NewTracker(&FakeStorage{})
+	if err != nil {
+		t.Fatalf("NewTracker() error = %v", err)
+	}
+
+	record, err := tracker.AddRecord(TrackerRecord{Description: "Hotel", Amount: 100, Tags: []string{"Trip-Lisbon", "#business", "business"}})
+	if err != nil || !reflect.DeepEqual(record.Tags, []string{"business", "trip-lisbon"}) {
+		t.Fatalf("AddRecord() = %v, %v, want tags [business trip-lisbon]", record, err)
+	}
+	if _, err := tracker.AddRecord(TrackerRecord{Description: "Hotel", Amount: 100, Tags: []string{"trip lisbon"}}); !errors.Is(err, invalidTag) {
+		t.Errorf("AddRecord() with invalid tag error = %v, want %v", err, invalidTag)
+	}
+
+	record, err = tracker.UpdateRecord(record.Id, RecordChange{AddTags: []string{"food"}, RemoveTags: []string{"business"}})
+	if err != nil || !reflect.DeepEqual(record.Tags, []string{"food", "trip-lisbon"}) || record.Description != "Hotel" {
+		t.Errorf("UpdateRecord() = %v, %v, want tags [food trip-lisbon]", record, err)
+	}
+	record, err = tracker.UpdateRecord(record.Id, RecordChange{RemoveTags: []string{"food", "trip-lisbon"}})
+	if err != nil || record.Tags != nil {
+		t.Errorf("UpdateRecord() removing all tags = %v, %v, want no tags", record, err)
+	}
+}
+
+func TestTrackerPayeeAndNotes(t *testing.T) {
+	tracker, err := NewTracker(&FakeStorage{})
+	if err != nil {
+		t.Fatalf("NewTracker() error = %v", err)
+	}
+
+	record, err := tracker.AddRecord(TrackerRecord{Description: "Groceries", Amount: 40, Payee: " Grocer ", Notes: "weekly\nshopping"})
+	if err != nil || record.Payee != "Grocer" || record.Notes != "weekly\nshopping" {
+		t.Fatalf("AddRecord() = %v, %v, want payee Grocer and notes", record, err)
+	}
+
+	payee := "Market"
+	record, err = tracker.UpdateRecord(record.Id, RecordChange{Payee: &payee})
+	if err != nil || record.Payee != "Market" || record.Notes != "weekly\nshopping" {
+		t.Errorf("UpdateRecord() payee = %v, %v, want payee Market and unchanged notes", record, err)
+	}
+	empty := ""
+	record, err = tracker.UpdateRecord(record.Id, RecordChange{Notes: &empty})
+	if err != nil || record.Payee != "Market" || record.Notes != "" {
+		t.Errorf("UpdateRecord() clearing notes = %v, %v, want payee Market and no notes", record, err)
+	}
+}
+
+func TestTrackerAttachments(t *testing.T) {
+	tracker, err := NewTracker(&FakeStorage{})
+	if err != nil {
+		t.Fatalf("NewTracker() error = %v", err)
+	}
+	hotel, _ := tracker.AddRecord(TrackerRecord{Description: "Hotel", Amount: 100})
+	taxi, _ := tracker.AddRecord(TrackerRecord{Description: "Taxi", Amount: 15})
+
+	if _, err := tracker.Attach(hotel.Id, "receipt.pdf", strings.NewReader("receipt")); !errors.Is(err, noAttachments) {
+		t.Errorf("Attach() without store error = %v, want %v", err, noAttachments)
+	}
+
+	store := NewDirAttachmentStore(t.TempDir())
+	tracker.SetAttachments(store)
+	if _, err := tracker.Attach(hotel.Id, "../receipt.pdf", strings.NewReader("receipt")); !errors.Is(err, invalidAttachment) {
+		t.Errorf("Attach() with path error = %v, want %v", err, invalidAttachment)
+	}
+	if _, err := tracker.Attach(99, "receipt.pdf", strings.NewReader("receipt")); !errors.Is(err, recordNotFound) {
+		t.Errorf("Attach() to missing record error = %v, want %v", err, recordNotFound)
+	}
+
+	hotel, err = tracker.Attach(hotel.Id, "receipt.pdf", strings.NewReader("receipt"))
+	if err != nil || len(hotel.Attachments) != 1 || hotel.Attachments[0].Name != "receipt.pdf" {
+		t.Fatalf("Attach() = %v, %v, want one attachment", hotel, err)
+	}
+	hotel, err = tracker.Attach(hotel.Id, "receipt.pdf", strings.NewReader("receipt"))
+	if err != nil || len(hotel.Attachments) != 1 {
+		t.Errorf("Attach() same file again = %v, %v, want one attachment", hotel, err)
+	}
+	taxi, err = tracker.Attach(taxi.Id, "taxi.pdf", strings.NewReader("receipt"))
+	if err != nil || taxi.Attachments[0].Key != hotel.Attachments[0].Key {
+		t.Errorf("Attach() same content to other record = %v, %v, want shared key", taxi, err)
+	}
+	taxi, _ = tracker.Attach(taxi.Id, "ticket.pdf", strings.NewReader("ticket"))
+
+	content, err := tracker.OpenAttachment(hotel.Attachments[0].Key)
+	if err != nil {
+		t.Fatalf("OpenAttachment() error = %v", err)
+	}
+	content.Close()
+
+	// deleting keeps content so the record can be restored from a backup
+	if err := tracker.Delete(hotel.Id); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := tracker.Delete(taxi.Id); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if keys, _ := store.Keys(); len(keys) != 2 {
+		t.Errorf("Keys() after deleting all records = %v, want 2 keys", keys)
+	}
+
+	ticket := taxi.Attachments[1].Key
+	removed, err := tracker.PruneAttachments(func(key string) bool {
+		return key == ticket
+	})
+	if err != nil || len(removed) != 1 || removed[0] != hotel.Attachments[0].Key {
+		t.Errorf("PruneAttachments() = %v, %v, want only the receipt removed", removed, err)
+	}
+	if keys, _ := store.Keys(); !reflect.DeepEqual(keys, []string{ticket}) {
+		t.Errorf("Keys() after prune = %v, want %v", keys, []string{ticket})
+	}
+}
+
+func TestTrackerTransfer(t *testing.T) {
+	storage := &FakeStorage{}
+	tracker, err := NewTracker(storage)
+	if err != nil {
+		t.Fatalf("NewTracker() error = %v", err)
+	}
+	lunch, _ := tracker.AddRecord(TrackerRecord{Description: "Lunch", Amount: 20, Account: "Cash"})
+	if lunch.Account != "cash" {
+		t.Errorf("AddRecord() account = %q, want %q", lunch.Account, "cash")
+	}
+
+	if _, _, err := tracker.Transfer("cash", "Cash", 100, "Withdrawal"); !errors.Is(err, invalidTransfer) {
+		t.Errorf("Transfer() to the same account error = %v, want %v", err, invalidTransfer)
+	}
+	if _, _, err := tracker.Transfer("my cash", "checking", 100, "Withdrawal"); !errors.Is(err, invalidAccount) {
+		t.Errorf("Transfer() from invalid account error = %v, want %v", err, invalidAccount)
+	}
+
+	out, in, err := tracker.Transfer("checking", "cash", 100, "Withdrawal")
+	if err != nil {
+		t.Fatalf("Transfer() error = %v", err)
+	}
+	if out.Id != 2 || out.Kind != KindTransferOut || out.Account != "checking" || out.Transfer != in.Uid {
+		t.Errorf("Transfer() out = %+v, want transfer-out of checking linked to %q", out, in.Uid)
+	}
+	if in.Id != 3 || in.Kind != KindTransferIn || in.Account != "cash" || in.Transfer != out.Uid {
+		t.Errorf("Transfer() in = %+v, want transfer-in to cash linked to %q", in, out.Uid)
+	}
+	if got := tracker.GetSummary(); got != 20 {
+		t.Errorf("GetSummary() = %d, want transfers not counted", got)
+	}
+
+	_, err = tracker.UpdateRecord(in.Id, RecordChange{Amount: 150})
+	if err != nil {
+		t.Fatalf("UpdateRecord() error = %v", err)
+	}
+	if updated, _ := tracker.Get(out.Id); updated.Amount != 150 {
+		t.Errorf("linked record amount = %d, want 150", updated.Amount)
+	}
+
+	if err := tracker.Delete(out.Id); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if records := tracker.GetAll(); len(records) != 1 || records[0].Id != lunch.Id {
+		t.Errorf("GetAll() after deleting transfer = %v, want only lunch", records)
+	}
+}
+
+func TestSearchRecords(t *testing.T) {
+	records := []TrackerRecord{
+		{Id: 1, Description: "Lunch", Payee: "Cafe Central"},
+		{Id: 2, Description: "Groceries", Notes: "bought coffee beans"},
+		{Id: 3, Description: "Hotel", Tags: []string{"trip-lisbon"}},
+		{Id: 4, Description: "Coffee"},
+	}
+
+	tests := []struct {
+		query string
+		want  []RecordId
+	}{
+		{query: "coffee", want: []RecordId{2, 4}},
+		{query: "CENTRAL", want: []RecordId{1}},
+		{query: "#trip", want: []RecordId{3}},
+		{query: "lisbon", want: []RecordId{3}},
+		{query: "tea", want: []RecordId{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got := make([]RecordId, 0)
+			for _, record := range SearchRecords(records, tt.query) {
+				got = append(got, record.Id)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SearchRecords(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestTrackerAdd(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -152,6 +462,44 @@ func TestTrackerAdd(t *testing.T) {
 	}
 }
 
+func TestTrackerAddUsesClock(t *testing.T) {
+	tests := []struct {
+		name      string
+		now       time.Time
+		wantMonth time.Month
+		wantYear  int
+	}{
+		{name: "LastSecondOfMonth", now: time.Date(2024, time.January, 31, 23, 59, 59, 0, time.UTC), wantMonth: time.January, wantYear: 2024},
+		{name: "FirstSecondOfMonth", now: time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC), wantMonth: time.February, wantYear: 2024},
+		{name: "LastSecondOfYear", now: time.Date(2024, time.December, 31, 23, 59, 59, 0, time.UTC), wantMonth: time.December, wantYear: 2024},
+		{name: "FirstSecondOfYear", now: time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC), wantMonth: time.January, wantYear: 2025},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &FakeStorage{}
+			tracker, _ := NewTrackerWithClock(storage, FixedClock(tt.now))
+			tracker.SetLocation(time.UTC)
+			record, err := tracker.Add("Test", 10)
+			if err != nil {
+				t.Fatalf("Tracker.Add() error = %v", err)
+			}
+			if !record.CreatedAt.Equal(tt.now) {
+				t.Errorf("Tracker.Add() CreatedAt = %v, want %v", record.CreatedAt, tt.now)
+			}
+			if got := tracker.GetSummaryByMonth(tt.wantMonth, tt.wantYear); got != 10 {
+				t.Errorf("Tracker.GetSummaryByMonth(%v, %d) = %v, want 10", tt.wantMonth, tt.wantYear, got)
+			}
+			if got := tracker.GetSummaryByYear(tt.wantYear); got != 10 {
+				t.Errorf("Tracker.GetSummaryByYear(%d) = %v, want 10", tt.wantYear, got)
+			}
+			if got := tracker.GetSummaryByPeriod(MonthPeriod(tt.wantMonth, tt.wantYear, tt.now.Location())); got != 10 {
+				t.Errorf("Tracker.GetSummaryByPeriod() = %v, want 10", got)
+			}
+		})
+	}
+}
+
 func TestTrackerDelete(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -173,6 +521,14 @@ func TestTrackerDelete(t *testing.T) {
 			setupData:   []TrackerRecord{{Id: 1}, {Id: 2}},
 			expectedRes: []TrackerRecord{{Id: 1}, {Id: 2}},
 		},
+		{
+			// the ledger is not saved, so a failing storage is not reached
+			name:        "RecordNotFoundNotSaved",
+			id:          3,
+			setupData:   []TrackerRecord{{Id: 1}, {Id: 2}},
+			storageErr:  errors.New("test storage error"),
+			expectedRes: []TrackerRecord{{Id: 1}, {Id: 2}},
+		},
 		{
 			name:        "StorageError",
 			id:          1,
@@ -347,6 +703,11 @@ func TestTrackerGetSummary(t *testing.T) {
 			data: []TrackerRecord{{Amount: 100}, {Amount: 200}, {Amount: 300}},
 			want: 600,
 		},
+		{
+			name: "IncomeExcluded",
+			data: []TrackerRecord{{Amount: 100}, {Amount: 500, Kind: KindIncome}},
+			want: 100,
+		},
 	}
 
 	for _, tt := range tests {
@@ -389,6 +750,7 @@ func TestTrackerGetSummaryByMonth(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			storage := &FakeStorage{records: tt.data}
 			tracker, _ := NewTracker(storage)
+			tracker.SetLocation(time.UTC)
 			if got := tracker.GetSummaryByMonth(tt.month, 2024); got != tt.want {
 				t.Errorf("Tracker.GetSummaryByMonth() = %v, want %v", got, tt.want)
 			}
@@ -441,9 +803,271 @@ func TestTrackerGetSummaryByYear(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			storage := &FakeStorage{records: tt.data}
 			tracker, _ := NewTracker(storage)
+			tracker.SetLocation(time.UTC)
 			if got := tracker.GetSummaryByYear(tt.year); got != tt.want {
 				t.Errorf("Tracker.GetSummaryByYear() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestTrackerReportingLocation(t *testing.T) {
+	newYork := time.FixedZone("UTC-5", -5*60*60)
+	// 2024-01-01 04:30 in UTC
+	data := []TrackerRecord{{Amount: 10, CreatedAt: time.Date(2023, 12, 31, 23, 30, 0, 0, newYork)}}
+	tests := []struct {
+		name      string
+		location  *time.Location
+		wantMonth time.Month
+		wantYear  int
+	}{
+		{name: "OriginalOffset", location: newYork, wantMonth: time.December, wantYear: 2023},
+		{name: "Utc", location: time.UTC, wantMonth: time.January, wantYear: 2024},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker, _ := NewTracker(&FakeStorage{records: data})
+			tracker.SetLocation(tt.location)
+			if got := tracker.GetSummaryByMonth(tt.wantMonth, tt.wantYear); got != 10 {
+				t.Errorf("Tracker.GetSummaryByMonth(%v, %d) = %v, want 10", tt.wantMonth, tt.wantYear, got)
+			}
+			if got := tracker.GetSummaryByYear(tt.wantYear); got != 10 {
+				t.Errorf("Tracker.GetSummaryByYear(%d) = %v, want 10", tt.wantYear, got)
+			}
+			if got := tracker.GetSummaryByPeriod(MonthPeriod(tt.wantMonth, tt.wantYear, tt.location)); got != 10 {
+				t.Errorf("Tracker.GetSummaryByPeriod() = %v, want 10", got)
+			}
+			if _, offset := tracker.GetAll()[0].CreatedAt.Zone(); offset != -5*60*60 {
+				t.Errorf("CreatedAt offset = %d, want original offset", offset)
+			}
+		})
+	}
+}
+
+func TestTrackerGetByPeriod(t *testing.T) {
+	data := []TrackerRecord{
+		{Id: 1, CreatedAt: time.Date(2023, 12, 31, 23, 59, 59, 0, time.UTC)},
+		{Id: 2, CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Id: 3, CreatedAt: time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)},
+		{Id: 4, CreatedAt: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	tests := []struct {
+		name   string
+		period Period
+		want   []RecordId
+	}{
+		{name: "Unbounded", period: Period{}, want: []RecordId{1, 2, 3, 4}},
+		{
+			name:   "Month",
+			period: Period{From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+			want:   []RecordId{2, 3},
+		},
+		{
+			name:   "OpenEnd",
+			period: Period{From: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)},
+			want:   []RecordId{3, 4},
+		},
+		{
+			name:   "NoMatches",
+			period: Period{From: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+			want:   []RecordId{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &FakeStorage{records: data}
+			tracker, _ := NewTracker(storage)
+			ids := make([]RecordId, 0)
+			for _, record := range tracker.GetByPeriod(tt.period) {
+				ids = append(ids, record.Id)
+			}
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("Tracker.GetByPeriod() = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrackerGetSummaryByPeriod(t *testing.T) {
+	data := []TrackerRecord{
+		{Amount: 100, CreatedAt: time.Date(2024, 12, 29, 12, 0, 0, 0, time.UTC)},
+		{Amount: 200, CreatedAt: time.Date(2024, 12, 31, 23, 59, 59, 0, time.UTC)},
+		{Amount: 300, CreatedAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Amount: 400, CreatedAt: time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)},
+	}
+	firstWeek2025, _ := IsoWeekPeriod(1, 2025, time.UTC)
+	lastQuarter2024, _ := QuarterPeriod(4, 2024, time.UTC)
+	tests := []struct {
+		name   string
+		period Period
+		want   uint
+	}{
+		{name: "Unbounded", period: Period{}, want: 1000},
+		{name: "IsoWeekAcrossNewYear", period: firstWeek2025, want: 500},
+		{name: "Quarter", period: lastQuarter2024, want: 300},
+		{name: "Year", period: YearPeriod(2025, time.UTC), want: 700},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &FakeStorage{records: data}
+			tracker, _ := NewTracker(storage)
+			if got := tracker.GetSummaryByPeriod(tt.period); got != tt.want {
+				t.Errorf("Tracker.GetSummaryByPeriod() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrackerGetBreakdown(t *testing.T) {
+	data := []TrackerRecord{
+		{Amount: 100, CreatedAt: time.Date(2023, 12, 31, 23, 0, 0, 0, time.UTC)},
+		{Amount: 200, CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Amount: 300, CreatedAt: time.Date(2024, 1, 31, 23, 59, 59, 0, time.UTC)},
+		{Amount: 400, CreatedAt: time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)},
+		{Amount: 500, CreatedAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	storage := &FakeStorage{records: data}
+	tracker, _ := NewTracker(storage)
+
+	totals := tracker.GetBreakdown(YearPeriod(2024, time.UTC), ByMonth)
+	if len(totals) != 12 {
+		t.Fatalf("Tracker.GetBreakdown() returned %d periods, want 12", len(totals))
+	}
+
+	expected := map[int]PeriodTotal{
+		0: {Total: 500, Count: 2},
+		2: {Total: 400, Count: 1},
+	}
+	for i, total := range totals {
+		want := expected[i]
+		if total.Total != want.Total || total.Count != want.Count {
+			t.Errorf("Tracker.GetBreakdown()[%d] = %d/%d, want %d/%d", i, total.Total, total.Count, want.Total, want.Count)
+		}
+	}
+	if average := totals[0].Average(); average != 250 {
+		t.Errorf("PeriodTotal.Average() = %v, want 250", average)
+	}
+	if average := totals[1].Average(); average != 0 {
+		t.Errorf("PeriodTotal.Average() for empty period = %v, want 0", average)
+	}
+}
+
+func TestTrackerFailedSaveKeepsState(t *testing.T) {
+	setupData := []TrackerRecord{{Id: 1, Description: "InitialDescription", Amount: 100}, {Id: 2, Description: "Second", Amount: 200}}
+	tests := []struct {
+		name   string
+		mutate func(tracker *Tracker) error
+	}{
+		{
+			name: "Add",
+			mutate: func(tracker *Tracker) error {
+				_, err := tracker.Add("New", 300)
+				return err
+			},
+		},
+		{
+			name: "Update",
+			mutate: func(tracker *Tracker) error {
+				_, err := tracker.Update(1, "UpdatedDescription", 150)
+				return err
+			},
+		},
+		{
+			name: "Delete",
+			mutate: func(tracker *Tracker) error {
+				return tracker.Delete(1)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// spare capacity lets in place modifications go unnoticed by slice header comparison
+			records := make([]TrackerRecord, len(setupData), len(setupData)+1)
+			copy(records, setupData)
+			storage := &FakeStorage{records: records, saveError: errors.New("test storage error")}
+			tracker, _ := NewTracker(storage)
+			snapshot := tracker.GetAll()
+
+			if err := test.mutate(tracker); err == nil {
+				t.Fatalf("expected storage error")
+			}
+
+			if !reflect.DeepEqual(tracker.GetAll(), setupData) {
+				t.Errorf("Got tracker data %v, expected %v", tracker.GetAll(), setupData)
+			}
+			if !reflect.DeepEqual(snapshot, setupData) {
+				t.Errorf("Got snapshot %v, expected %v", snapshot, setupData)
+			}
+			if extended := snapshot[:cap(snapshot)]; !reflect.DeepEqual(extended[len(setupData)], TrackerRecord{}) {
+				t.Errorf("Snapshot backing array modified: %v", extended)
+			}
+		})
+	}
+}
+
+func TestTrackerConcurrentAccess(t *testing.T) {
+	storage := &FakeStorage{records: []TrackerRecord{}}
+	tracker, _ := NewTracker(storage)
+
+	const workers = 8
+	const iterations = 50
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < iterations; j++ {
+				record, err := tracker.Add("Concurrent", 10)
+				if err != nil {
+					t.Errorf("Add() error = %v", err)
+					return
+				}
+				if _, err := tracker.Update(record.Id, "", 20); err != nil {
+					t.Errorf("Update() error = %v", err)
+				}
+				if j%2 == 0 {
+					if err := tracker.Delete(record.Id); err != nil {
+						t.Errorf("Delete() error = %v", err)
+					}
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < iterations; j++ {
+				records := tracker.GetAll()
+				var sum uint
+				for _, record := range records {
+					sum += record.Amount
+				}
+				_ = tracker.GetSummary()
+				_ = tracker.GetByPeriod(Period{})
+				_ = tracker.GetBreakdown(YearPeriod(time.Now().Year(), time.Local), ByMonth)
+			}
+		}()
+	}
+	wg.Wait()
+
+	records := tracker.GetAll()
+	if len(records) != workers*iterations/2 {
+		t.Errorf("Got %d records, expected %d", len(records), workers*iterations/2)
+	}
+	ids := make(map[RecordId]bool)
+	for _, record := range records {
+		if ids[record.Id] {
+			t.Errorf("Duplicate record id found: %v", record.Id)
+		}
+		ids[record.Id] = true
+		if record.Amount != 20 {
+			t.Errorf("Got record %v, expected amount 20", record)
+		}
+	}
+	if !reflect.DeepEqual(storage.records, records) {
+		t.Errorf("Storage diverged from tracker data")
+	}
+}
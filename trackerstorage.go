@@ -4,3 +4,16 @@ type TrackerStorage interface {
 	ReadAll() ([]TrackerRecord, error)
 	Save(records []TrackerRecord) error
 }
+
+// WarningStorage is implemented by storages that can load a ledger partially,
+// a tracker over a storage that skipped rows refuses changes.
+type WarningStorage interface {
+	Warnings() []LedgerIssue
+}
+
+// IdStorage is implemented by storages that keep the highest ID ever assigned,
+// so a tracker does not reuse IDs of deleted records.
+type IdStorage interface {
+	LastId() RecordId
+	SetLastId(id RecordId)
+}
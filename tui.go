@@ -0,0 +1,465 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// The terminal interface is split into a model holding the whole screen state,
+// update applying a key press to the model and view rendering the model to text.
+// Changes of records are returned from update as actions and applied to the tracker by the caller.
+
+func TuiCmd(_ *flag.FlagSet, env *Env) func(tracker *Tracker) error {
+	return func(tracker *Tracker) error {
+		return runTui(env, tracker)
+	}
+}
+
+func runTui(env *Env, tracker *Tracker) error {
+	stdin, ok := terminalFile(env.Stdin)
+	if _, isTerminal := terminalFile(env.Stdout); !ok || !isTerminal {
+		return errors.New("tui requires a terminal")
+	}
+	restore, err := enableRawMode(stdin)
+	if err != nil {
+		fmt.Fprintf(env.Stderr, "error switching terminal to raw mode: %v\n", err)
+		return err
+	}
+	defer restore()
+
+	// alternate screen buffer, hidden cursor
+	fmt.Fprint(env.Stdout, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(env.Stdout, "\x1b[?25h\x1b[?1049l")
+
+	in := bufio.NewReader(stdin)
+	width, height := terminalSize(stdin)
+	model := newTuiModel(inLocation(tracker.GetAll(), env.Location), width, height)
+	for {
+		renderTui(env.Stdout, model.view())
+
+		key, err := readKey(in)
+		if err != nil {
+			return err
+		}
+		width, height = terminalSize(stdin)
+		model = model.withSize(width, height)
+
+		var action tuiAction
+		model, action = model.update(key)
+		switch action.kind {
+		case tuiQuitAction:
+			return nil
+		case tuiAddAction:
+			_, err = tracker.Add(action.description, action.amount)
+		case tuiUpdateAction:
+			_, err = tracker.Update(action.id, action.description, action.amount)
+		case tuiDeleteAction:
+			err = tracker.Delete(action.id)
+		}
+		if err != nil {
+			model.message = err.Error()
+		}
+		model = model.withRecords(inLocation(tracker.GetAll(), env.Location))
+	}
+}
+
+func renderTui(w io.Writer, lines []string) {
+	var screen strings.Builder
+	screen.WriteString("\x1b[H")
+	for i, line := range lines {
+		if i > 0 {
+			screen.WriteString("\r\n")
+		}
+		screen.WriteString(line)
+		screen.WriteString("\x1b[K")
+	}
+	screen.WriteString("\x1b[J")
+	fmt.Fprint(w, screen.String())
+}
+
+type tuiMode int
+
+const (
+	tuiList tuiMode = iota
+	tuiFilter
+	tuiAdd
+	tuiEdit
+	tuiConfirmDelete
+)
+
+const (
+	tuiDescriptionField = iota
+	tuiAmountField
+)
+
+type tuiActionKind int
+
+const (
+	tuiNoAction tuiActionKind = iota
+	tuiAddAction
+	tuiUpdateAction
+	tuiDeleteAction
+	tuiQuitAction
+)
+
+type tuiAction struct {
+	kind        tuiActionKind
+	id          RecordId
+	description string
+	amount      uint
+}
+
+type tuiModel struct {
+	records  []TrackerRecord
+	filter   string
+	selected int
+	offset   int
+	mode     tuiMode
+	fields   [2]string
+	focus    int
+	editId   RecordId
+	editKind RecordKind
+	message  string
+	width    int
+	height   int
+}
+
+func newTuiModel(records []TrackerRecord, width, height int) tuiModel {
+	return tuiModel{records: records, width: width, height: height}
+}
+
+// withRecords replaces records after the tracker was changed, keeping the selection in range.
+func (m tuiModel) withRecords(records []TrackerRecord) tuiModel {
+	m.records = records
+	m.selected = min(m.selected, max(len(m.visible())-1, 0))
+	return m
+}
+
+func (m tuiModel) withSize(width, height int) tuiModel {
+	m.width = width
+	m.height = height
+	return m.scrolled()
+}
+
+// visible returns records matching the filter, the newest first.
+func (m tuiModel) visible() []TrackerRecord {
+	filter := strings.ToLower(m.filter)
+	records := make([]TrackerRecord, 0, len(m.records))
+	for i := len(m.records) - 1; i >= 0; i-- {
+		if strings.Contains(strings.ToLower(m.records[i].Description), filter) {
+			records = append(records, m.records[i])
+		}
+	}
+	return records
+}
+
+// listHeight is the number of rows available for records: title, header and two status lines are reserved.
+func (m tuiModel) listHeight() int {
+	return max(m.height-4, 1)
+}
+
+func (m tuiModel) scrolled() tuiModel {
+	if m.selected < m.offset {
+		m.offset = m.selected
+	}
+	if m.selected >= m.offset+m.listHeight() {
+		m.offset = m.selected - m.listHeight() + 1
+	}
+	return m
+}
+
+func (m tuiModel) update(key keyEvent) (tuiModel, tuiAction) {
+	switch m.mode {
+	case tuiFilter:
+		return m.updateFilter(key), tuiAction{}
+	case tuiAdd, tuiEdit:
+		return m.updateForm(key)
+	case tuiConfirmDelete:
+		return m.updateConfirm(key)
+	default:
+		return m.updateList(key)
+	}
+}
+
+func (m tuiModel) updateList(key keyEvent) (tuiModel, tuiAction) {
+	m.message = ""
+	visible := m.visible()
+	last := max(len(visible)-1, 0)
+
+	switch {
+	case key.code == keyUp || (key.code == keyRune && key.r == 'k'):
+		m.selected = max(m.selected-1, 0)
+	case key.code == keyDown || (key.code == keyRune && key.r == 'j'):
+		m.selected = min(m.selected+1, last)
+	case key.code == keyPageUp:
+		m.selected = max(m.selected-m.listHeight(), 0)
+	case key.code == keyPageDown:
+		m.selected = min(m.selected+m.listHeight(), last)
+	case key.code == keyHome:
+		m.selected = 0
+	case key.code == keyEnd:
+		m.selected = last
+	case key.code == keyEscape:
+		m.filter = ""
+		m.selected = 0
+	case key.code == keyRune && key.r == '/':
+		m.mode = tuiFilter
+	case key.code == keyRune && key.r == 'a':
+		m.mode = tuiAdd
+		m.fields = [2]string{}
+		m.focus = tuiDescriptionField
+	case (key.code == keyRune && key.r == 'e') || key.code == keyEnter:
+		if len(visible) > 0 {
+			record := visible[m.selected]
+			m.mode = tuiEdit
+			m.editId = record.Id
+			m.editKind = record.Kind
+			m.fields = [2]string{record.Description, strconv.FormatUint(uint64(record.Amount), 10)}
+			m.focus = tuiDescriptionField
+		}
+	case (key.code == keyRune && key.r == 'd') || key.code == keyDelete:
+		if len(visible) > 0 {
+			m.mode = tuiConfirmDelete
+		}
+	case (key.code == keyRune && key.r == 'q') || (key.code == keyCtrl && key.r == 'c'):
+		return m, tuiAction{kind: tuiQuitAction}
+	}
+	return m.scrolled(), tuiAction{}
+}
+
+func (m tuiModel) updateFilter(key keyEvent) tuiModel {
+	switch key.code {
+	case keyRune:
+		m.filter += string(key.r)
+	case keyBackspace:
+		m.filter = dropLastRune(m.filter)
+	case keyEscape:
+		m.filter = ""
+		m.mode = tuiList
+	case keyEnter, keyDown, keyUp:
+		m.mode = tuiList
+	}
+	m.selected = 0
+	m.offset = 0
+	return m
+}
+
+func (m tuiModel) updateForm(key keyEvent) (tuiModel, tuiAction) {
+	switch key.code {
+	case keyRune:
+		if m.focus == tuiAmountField && (key.r < '0' || key.r > '9') {
+			break
+		}
+		m.fields[m.focus] += string(key.r)
+	case keyBackspace:
+		m.fields[m.focus] = dropLastRune(m.fields[m.focus])
+	case keyTab, keyUp, keyDown:
+		m.focus = 1 - m.focus
+	case keyEscape:
+		m.mode = tuiList
+		m.message = ""
+	case keyEnter:
+		description := strings.TrimSpace(m.fields[tuiDescriptionField])
+		amount, err := strconv.ParseUint(m.fields[tuiAmountField], 10, 32)
+		if description == "" {
+			m.message = "invalid description"
+			break
+		}
+		if err != nil || amount == 0 {
+			m.message = "invalid amount"
+			break
+		}
+		action := tuiAction{kind: tuiAddAction, description: description, amount: uint(amount)}
+		if m.mode == tuiEdit {
+			action.kind = tuiUpdateAction
+			action.id = m.editId
+		}
+		m.mode = tuiList
+		m.message = ""
+		return m, action
+	}
+	return m, tuiAction{}
+}
+
+func (m tuiModel) updateConfirm(key keyEvent) (tuiModel, tuiAction) {
+	m.mode = tuiList
+	if key.code == keyRune && (key.r == 'y' || key.r == 'Y') {
+		return m, tuiAction{kind: tuiDeleteAction, id: m.visible()[m.selected].Id}
+	}
+	return m, tuiAction{}
+}
+
+func dropLastRune(s string) string {
+	_, size := utf8.DecodeLastRuneInString(s)
+	return s[:len(s)-size]
+}
+
+// tuiTotalsWidth is the width of the monthly totals panel, it is hidden on narrow screens.
+const tuiTotalsWidth = 22
+
+// view renders exactly height lines, each at most width characters long not counting color escapes.
+func (m tuiModel) view() []string {
+	listWidth := m.width
+	showTotals := m.width >= 60
+	if showTotals {
+		listWidth = m.width - tuiTotalsWidth - 1
+	}
+
+	lines := make([]string, 0, m.height)
+	lines = append(lines, fitText(" Expense Tracker", m.width))
+
+	left := m.viewList(listWidth)
+	if m.mode == tuiAdd || m.mode == tuiEdit {
+		left = m.viewForm(listWidth)
+	}
+	var right []string
+	if showTotals {
+		right = m.viewTotals()
+	}
+	for i := 0; i < m.height-3; i++ {
+		line := ""
+		if i < len(left) {
+			line = left[i]
+		} else {
+			line = fitText("", listWidth)
+		}
+		if showTotals {
+			line += "│"
+			if i < len(right) {
+				line += right[i]
+			} else {
+				line += fitText("", tuiTotalsWidth)
+			}
+		}
+		lines = append(lines, line)
+	}
+
+	lines = append(lines, fitText(m.viewStatus(), m.width), fitText(m.viewHelp(), m.width))
+	return lines[:min(len(lines), m.height)]
+}
+
+func (m tuiModel) viewList(width int) []string {
+	descriptionWidth := max(width-28, 4)
+	row := func(id, date, description, amount string) string {
+		return fitText(fmt.Sprintf(" %4s  %-10s  %s %8s", id, date, fitText(description, descriptionWidth), amount), width)
+	}
+
+	lines := []string{row("ID", "Date", "Description", "Amount")}
+	visible := m.visible()
+	end := min(m.offset+m.listHeight(), len(visible))
+	for i := m.offset; i < end; i++ {
+		record := visible[i]
+		line := row(strconv.FormatUint(uint64(record.Id), 10), record.CreatedAt.Format(time.DateOnly), record.Description, formatSignedAmount(record))
+		if i == m.selected {
+			line = "\x1b[7m" + line + "\x1b[0m"
+		}
+		lines = append(lines, line)
+	}
+	if len(visible) == 0 {
+		lines = append(lines, fitText(" No expenses", width))
+	}
+	return lines
+}
+
+func (m tuiModel) viewForm(width int) []string {
+	title := " Add expense"
+	if m.mode == tuiEdit {
+		title = fmt.Sprintf(" Edit %s %d", recordNoun(m.editKind), m.editId)
+	}
+	field := func(index int, label string) string {
+		cursor := " "
+		if m.focus == index {
+			cursor = "_"
+		}
+		return fitText(fmt.Sprintf(" %-12s %s%s", label, m.fields[index], cursor), width)
+	}
+	return []string{
+		fitText(title, width),
+		fitText("", width),
+		field(tuiDescriptionField, "Description:"),
+		field(tuiAmountField, "Amount:"),
+	}
+}
+
+// recordNoun names the kind of a record in titles and prompts.
+func recordNoun(kind RecordKind) string {
+	switch kind {
+	case KindIncome:
+		return "income"
+	case KindTransferIn, KindTransferOut:
+		return "transfer"
+	default:
+		return "expense"
+	}
+}
+
+// viewTotals sums filtered records by month, the latest months first.
+func (m tuiModel) viewTotals() []string {
+	totals := make(map[string]uint)
+	for _, record := range expensesOnly(m.visible()) {
+		totals[record.CreatedAt.Format("2006-01")] += record.Amount
+	}
+	months := make([]string, 0, len(totals))
+	for month := range totals {
+		months = append(months, month)
+	}
+	slices.Sort(months)
+	slices.Reverse(months)
+
+	lines := []string{fitText(" Monthly totals", tuiTotalsWidth)}
+	for _, month := range months {
+		lines = append(lines, fitText(fmt.Sprintf(" %s %11d", month, totals[month]), tuiTotalsWidth))
+	}
+	return lines
+}
+
+func (m tuiModel) viewStatus() string {
+	switch {
+	case m.mode == tuiConfirmDelete:
+		record := m.visible()[m.selected]
+		return fmt.Sprintf(" Delete %s %d %q? y/n", recordNoun(record.Kind), record.Id, record.Description)
+	case m.message != "":
+		return " " + m.message
+	case m.mode == tuiFilter || m.filter != "":
+		cursor := ""
+		if m.mode == tuiFilter {
+			cursor = "_"
+		}
+		return " Filter: " + m.filter + cursor
+	default:
+		return ""
+	}
+}
+
+func (m tuiModel) viewHelp() string {
+	switch m.mode {
+	case tuiFilter:
+		return " type to filter  Enter done  Esc clear"
+	case tuiAdd, tuiEdit:
+		return " Tab next field  Enter save  Esc cancel"
+	case tuiConfirmDelete:
+		return " y delete  any other key cancel"
+	default:
+		return " ↑↓ move  a add  e edit  d delete  / filter  q quit"
+	}
+}
+
+// fitText pads or truncates text to exactly width characters.
+func fitText(text string, width int) string {
+	length := utf8.RuneCountInString(text)
+	if length <= width {
+		return text + strings.Repeat(" ", width-length)
+	}
+	if width <= 0 {
+		return ""
+	}
+	return string([]rune(text)[:width-1]) + "…"
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func runeKey(r rune) keyEvent {
+	return keyEvent{code: keyRune, r: r}
+}
+
+func typeKeys(m tuiModel, text string) tuiModel {
+	for _, r := range text {
+		m, _ = m.update(runeKey(r))
+	}
+	return m
+}
+
+func TestTuiNavigation(t *testing.T) {
+	m := newTuiModel(testRecords(), 80, 6)
+
+	if visible := m.visible(); visible[0].Id != 3 || visible[2].Id != 1 {
+		t.Fatalf("visible() = %v, want the newest first", visible)
+	}
+
+	m, _ = m.update(keyEvent{code: keyDown})
+	m, _ = m.update(keyEvent{code: keyDown})
+	m, _ = m.update(keyEvent{code: keyDown})
+	if m.selected != 2 {
+		t.Errorf("selected = %d after moving past the end, want 2", m.selected)
+	}
+	// 6 lines leave 2 rows for records
+	if m.offset != 1 {
+		t.Errorf("offset = %d, want 1", m.offset)
+	}
+
+	m, _ = m.update(keyEvent{code: keyHome})
+	if m.selected != 0 || m.offset != 0 {
+		t.Errorf("selected/offset = %d/%d after Home, want 0/0", m.selected, m.offset)
+	}
+
+	if _, action := m.update(runeKey('q')); action.kind != tuiQuitAction {
+		t.Errorf("q action = %v, want quit", action)
+	}
+}
+
+func TestTuiFilter(t *testing.T) {
+	m := newTuiModel(testRecords(), 80, 10)
+
+	m, _ = m.update(runeKey('/'))
+	m = typeKeys(m, "Nx")
+	m, _ = m.update(keyEvent{code: keyBackspace})
+	if m.mode != tuiFilter || m.filter != "N" {
+		t.Fatalf("mode/filter = %v/%q, want filter mode with N", m.mode, m.filter)
+	}
+	if visible := m.visible(); len(visible) != 2 || visible[0].Id != 2 || visible[1].Id != 1 {
+		t.Errorf("visible() = %v, want records 2 and 1", visible)
+	}
+
+	m, _ = m.update(keyEvent{code: keyEnter})
+	if m.mode != tuiList || m.filter != "N" {
+		t.Errorf("mode/filter = %v/%q after Enter, want list mode keeping filter", m.mode, m.filter)
+	}
+
+	m, _ = m.update(keyEvent{code: keyEscape})
+	if m.filter != "" || len(m.visible()) != 3 {
+		t.Errorf("filter = %q after Esc, want cleared", m.filter)
+	}
+}
+
+func TestTuiAddForm(t *testing.T) {
+	m := newTuiModel(testRecords(), 80, 10)
+
+	m, _ = m.update(runeKey('a'))
+	m = typeKeys(m, "Coffee")
+	m, _ = m.update(keyEvent{code: keyTab})
+	m = typeKeys(m, "1x2")
+
+	m, action := m.update(keyEvent{code: keyEnter})
+	if action != (tuiAction{kind: tuiAddAction, description: "Coffee", amount: 12}) {
+		t.Errorf("add action = %+v, want Coffee with amount 12", action)
+	}
+	if m.mode != tuiList {
+		t.Errorf("mode = %v after submit, want list", m.mode)
+	}
+}
+
+func TestTuiFormValidation(t *testing.T) {
+	m := newTuiModel(testRecords(), 80, 10)
+
+	m, _ = m.update(runeKey('a'))
+	m, action := m.update(keyEvent{code: keyEnter})
+	if action.kind != tuiNoAction || m.message != "invalid description" || m.mode != tuiAdd {
+		t.Errorf("empty form action/message/mode = %v/%q/%v", action, m.message, m.mode)
+	}
+
+	m = typeKeys(m, "Coffee")
+	m, action = m.update(keyEvent{code: keyEnter})
+	if action.kind != tuiNoAction || m.message != "invalid amount" {
+		t.Errorf("form without amount action/message = %v/%q", action, m.message)
+	}
+
+	m, _ = m.update(keyEvent{code: keyEscape})
+	if m.mode != tuiList || m.message != "" {
+		t.Errorf("mode/message after Esc = %v/%q", m.mode, m.message)
+	}
+}
+
+func TestTuiEditForm(t *testing.T) {
+	m := newTuiModel(testRecords(), 80, 10)
+
+	m, _ = m.update(keyEvent{code: keyDown})
+	m, _ = m.update(runeKey('e'))
+	if m.mode != tuiEdit || m.editId != 2 || m.fields != [2]string{"Dinner", "30"} {
+		t.Fatalf("edit mode/id/fields = %v/%d/%v", m.mode, m.editId, m.fields)
+	}
+
+	m, _ = m.update(keyEvent{code: keyBackspace})
+	m = typeKeys(m, "rs")
+	m, _ = m.update(keyEvent{code: keyTab})
+	m, _ = m.update(keyEvent{code: keyBackspace})
+	m = typeKeys(m, "5")
+
+	_, action := m.update(keyEvent{code: keyEnter})
+	if action != (tuiAction{kind: tuiUpdateAction, id: 2, description: "Dinners", amount: 35}) {
+		t.Errorf("update action = %+v", action)
+	}
+}
+
+func TestTuiConfirmDelete(t *testing.T) {
+	m := newTuiModel(testRecords(), 80, 10)
+
+	m, _ = m.update(runeKey('d'))
+	if m.mode != tuiConfirmDelete || !strings.Contains(m.viewStatus(), `Delete expense 3 "Coffee"?`) {
+		t.Fatalf("mode/status = %v/%q", m.mode, m.viewStatus())
+	}
+	cancelled, action := m.update(runeKey('n'))
+	if action.kind != tuiNoAction || cancelled.mode != tuiList {
+		t.Errorf("n action/mode = %v/%v, want cancelled", action, cancelled.mode)
+	}
+
+	m, action = m.update(runeKey('y'))
+	if action != (tuiAction{kind: tuiDeleteAction, id: 3}) {
+		t.Errorf("y action = %+v, want delete of 3", action)
+	}
+
+	m.selected = 2
+	m = m.withRecords(testRecords()[:2])
+	if m.selected != 1 {
+		t.Errorf("selected = %d after deleting, want 1", m.selected)
+	}
+}
+
+func TestTuiView(t *testing.T) {
+	m := newTuiModel(testRecords(), 80, 8)
+	m, _ = m.update(keyEvent{code: keyDown})
+
+	lines := m.view()
+	if len(lines) != 8 {
+		t.Fatalf("view() returned %d lines, want 8", len(lines))
+	}
+	for i, line := range lines {
+		plain := strings.NewReplacer("\x1b[7m", "", "\x1b[0m", "").Replace(line)
+		if width := utf8.RuneCountInString(plain); width != 80 {
+			t.Errorf("line %d is %d characters wide, want 80: %q", i, width, plain)
+		}
+	}
+
+	screen := strings.Join(lines, "\n")
+	for _, expected := range []string{
+		"Monthly totals",
+		"2024-03           5",
+		"2024-02          30",
+		"2024-01          20",
+		"\x1b[7m    2  2024-02-15  Dinner",
+		"a add  e edit  d delete",
+	} {
+		if !strings.Contains(screen, expected) {
+			t.Errorf("view() does not contain %q:\n%s", expected, screen)
+		}
+	}
+
+	narrow := newTuiModel(testRecords(), 40, 8).view()
+	if strings.Contains(strings.Join(narrow, "\n"), "Monthly totals") {
+		t.Errorf("narrow view() should hide monthly totals")
+	}
+}
+
+func TestTuiIncome(t *testing.T) {
+	records := append(testRecords(), TrackerRecord{Id: 4, Description: "Salary", Amount: 1000, CreatedAt: time.Date(2024, 3, 20, 9, 0, 0, 0, time.Local), Kind: KindIncome})
+	m := newTuiModel(records, 80, 8)
+
+	screen := strings.Join(m.view(), "\n")
+	if !strings.Contains(screen, "Salary") || !strings.Contains(screen, "+1000") || !strings.Contains(screen, "2024-03           5") {
+		t.Errorf("view() does not show signed income outside of totals:\n%s", screen)
+	}
+
+	m, _ = m.update(runeKey('e'))
+	if title := m.viewForm(80)[0]; !strings.HasPrefix(title, " Edit income 4") {
+		t.Errorf("form title = %q, want income", title)
+	}
+}
+
+func TestFitText(t *testing.T) {
+	tests := []struct {
+		text  string
+		width int
+		want  string
+	}{
+		{text: "abc", width: 5, want: "abc  "},
+		{text: "abcdef", width: 4, want: "abc…"},
+		{text: "äöü", width: 3, want: "äöü"},
+		{text: "abc", width: 0, want: ""},
+	}
+	for _, tt := range tests {
+		if got := fitText(tt.text, tt.width); got != tt.want {
+			t.Errorf("fitText(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.want)
+		}
+	}
+}
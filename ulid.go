@@ -0,0 +1,51 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"strings"
+	"time"
+)
+
+// crockfordBase32 is the ULID alphabet, it skips I, L, O and U.
+const crockfordBase32 = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+const ulidLen = 26
+
+// newUlid returns a ULID: 48-bit millisecond timestamp followed by 80 random bits,
+// ULIDs created in different milliseconds sort by creation time.
+func newUlid(now time.Time) (string, error) {
+	var id [16]byte
+	binary.BigEndian.PutUint64(id[:8], uint64(now.UnixMilli())<<16)
+	_, err := rand.Read(id[6:])
+	if err != nil {
+		return "", err
+	}
+	return encodeUlid(id), nil
+}
+
+// encodeUlid encodes 128 bits as 26 characters of 5 bits, the first character holds the top 3 bits.
+func encodeUlid(id [16]byte) string {
+	hi := binary.BigEndian.Uint64(id[:8])
+	lo := binary.BigEndian.Uint64(id[8:])
+	var text [ulidLen]byte
+	for i := ulidLen - 1; i >= 0; i-- {
+		text[i] = crockfordBase32[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(text[:])
+}
+
+// isUlid reports whether s is a ULID in canonical upper case form.
+func isUlid(s string) bool {
+	if len(s) != ulidLen || s[0] > '7' {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(crockfordBase32, s[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
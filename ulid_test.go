@@ -0,0 +1,75 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncodeUlid(t *testing.T) {
+	tests := []struct {
+		name string
+		id   [16]byte
+		want string
+	}{
+		{name: "Zero", want: "00000000000000000000000000"},
+		{name: "Max", id: [16]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, want: "7ZZZZZZZZZZZZZZZZZZZZZZZZZ"},
+		{name: "LowestBit", id: [16]byte{15: 1}, want: "00000000000000000000000001"},
+		{name: "HighestBit", id: [16]byte{0: 0x80}, want: "40000000000000000000000000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := encodeUlid(tt.id); got != tt.want {
+				t.Errorf("encodeUlid() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewUlid(t *testing.T) {
+	// timestamp of the example in the ULID specification
+	now := time.UnixMilli(1469918176385)
+	first, err := newUlid(now)
+	if err != nil {
+		t.Fatalf("newUlid() error = %v", err)
+	}
+	second, err := newUlid(now)
+	if err != nil {
+		t.Fatalf("newUlid() error = %v", err)
+	}
+
+	if !strings.HasPrefix(first, "01ARYZ6S41") || !isUlid(first) {
+		t.Errorf("newUlid() = %s, want valid ULID with timestamp 01ARYZ6S41", first)
+	}
+	if first == second {
+		t.Errorf("newUlid() returned %s twice", first)
+	}
+	later, _ := newUlid(now.Add(time.Millisecond))
+	if later <= first || later <= second {
+		t.Errorf("newUlid() of a later time = %s, want greater than %s and %s", later, first, second)
+	}
+}
+
+func TestIsUlid(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{value: "01ARYZ6S41TSV4RRFFQ69G5FAV", want: true},
+		{value: "7ZZZZZZZZZZZZZZZZZZZZZZZZZ", want: true},
+		{value: "80000000000000000000000000", want: false},
+		{value: "01arYZ6S41TSV4RRFFQ69G5FAV", want: false},
+		{value: "01ARYZ6S41TSV4RRFFQ69G5FAI", want: false},
+		{value: "01ARYZ6S41TSV4RRFFQ69G5FA", want: false},
+		{value: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := isUlid(tt.value); got != tt.want {
+				t.Errorf("isUlid(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
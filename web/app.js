@@ -0,0 +1,220 @@
+"use strict";
+
+const tableBody = document.querySelector("#expenses tbody");
+const totalCell = document.querySelector("#total");
+const incomeCell = document.querySelector("#income");
+const netCell = document.querySelector("#net");
+const incomeRows = document.querySelectorAll("#expenses tfoot .income");
+const monthsBody = document.querySelector("#months tbody");
+const filters = document.querySelector("#filters");
+const addForm = document.querySelector("#add");
+const errorLine = document.querySelector("#error");
+
+let expenses = [];
+
+async function request(method, path, body) {
+    const options = {method, headers: {}};
+    if (body !== undefined) {
+        options.headers["Content-Type"] = "application/json";
+        options.body = JSON.stringify(body);
+    }
+    const response = await fetch(path, options);
+    if (!response.ok) {
+        const data = await response.json().catch(() => ({error: response.statusText}));
+        throw new Error(data.error);
+    }
+    return response.status === 204 ? null : response.json();
+}
+
+function showError(error) {
+    errorLine.textContent = error ? error.message : "";
+    errorLine.hidden = !error;
+}
+
+async function load() {
+    const params = new URLSearchParams();
+    for (const name of ["from", "to"]) {
+        if (filters.elements[name].value) {
+            params.set(name, filters.elements[name].value);
+        }
+    }
+    try {
+        expenses = await request("GET", "/expenses?" + params);
+        showError(null);
+    } catch (error) {
+        expenses = [];
+        showError(error);
+    }
+    render();
+}
+
+function visibleExpenses() {
+    const search = filters.elements.search.value.trim().toLowerCase();
+    return expenses.filter(expense => expense.description.toLowerCase().includes(search));
+}
+
+// records without kind are expenses, transfers between accounts are neither spent nor received
+function isExpense(record) {
+    return !record.kind;
+}
+
+function isIncome(record) {
+    return record.kind === "income";
+}
+
+function signedAmount(record) {
+    return isIncome(record) || record.kind === "transfer-in" ? `+${record.amount}` : record.amount;
+}
+
+function signed(value) {
+    return value > 0 ? `+${value}` : value;
+}
+
+function sumAmounts(records, include) {
+    return records.filter(include).reduce((sum, record) => sum + record.amount, 0);
+}
+
+function localDate(value) {
+    const date = new Date(value);
+    const month = String(date.getMonth() + 1).padStart(2, "0");
+    const day = String(date.getDate()).padStart(2, "0");
+    return `${date.getFullYear()}-${month}-${day}`;
+}
+
+function cell(row, text, className) {
+    const td = row.insertCell();
+    td.textContent = text;
+    if (className) {
+        td.className = className;
+    }
+    return td;
+}
+
+function button(parent, text, onClick) {
+    const element = document.createElement("button");
+    element.type = "button";
+    element.textContent = text;
+    element.addEventListener("click", onClick);
+    parent.append(element);
+    return element;
+}
+
+function render() {
+    const visible = visibleExpenses();
+
+    tableBody.replaceChildren();
+    for (const expense of visible) {
+        renderRow(tableBody.insertRow(), expense);
+    }
+    const spent = sumAmounts(visible, isExpense);
+    const received = sumAmounts(visible, isIncome);
+    totalCell.textContent = spent;
+    // income and net are shown only when there was income, like in the summary command
+    incomeCell.textContent = received;
+    netCell.textContent = signed(received - spent);
+    for (const row of incomeRows) {
+        row.hidden = received === 0;
+    }
+
+    const months = new Map();
+    for (const record of visible) {
+        if (!isExpense(record) && !isIncome(record)) {
+            continue;
+        }
+        const month = localDate(record.date).slice(0, 7);
+        const totals = months.get(month) || {count: 0, expenses: 0, income: 0};
+        if (isIncome(record)) {
+            totals.income += record.amount;
+        } else {
+            totals.count++;
+            totals.expenses += record.amount;
+        }
+        months.set(month, totals);
+    }
+    monthsBody.replaceChildren();
+    for (const month of [...months.keys()].sort().reverse()) {
+        const totals = months.get(month);
+        const row = monthsBody.insertRow();
+        cell(row, month);
+        cell(row, totals.count, "number");
+        cell(row, totals.expenses, "number");
+        cell(row, totals.income, "number");
+        cell(row, signed(totals.income - totals.expenses), "number");
+    }
+}
+
+function renderRow(row, expense) {
+    row.replaceChildren();
+    cell(row, expense.id);
+    cell(row, localDate(expense.date));
+    cell(row, expense.description);
+    cell(row, signedAmount(expense), "number");
+
+    const actions = cell(row, "");
+    button(actions, "Edit", () => renderEditRow(row, expense));
+    button(actions, "Delete", async () => {
+        if (!confirm(`Delete expense ${expense.id} "${expense.description}"?`)) {
+            return;
+        }
+        try {
+            await request("DELETE", `/expenses/${expense.id}`);
+            await load();
+        } catch (error) {
+            showError(error);
+        }
+    });
+}
+
+function renderEditRow(row, expense) {
+    row.replaceChildren();
+    cell(row, expense.id);
+    cell(row, localDate(expense.date));
+
+    const description = document.createElement("input");
+    description.value = expense.description;
+    cell(row, "").append(description);
+
+    const amount = document.createElement("input");
+    amount.type = "number";
+    amount.min = "1";
+    amount.step = "1";
+    amount.value = expense.amount;
+    cell(row, "", "number").append(amount);
+
+    const actions = cell(row, "");
+    button(actions, "Save", async () => {
+        try {
+            await request("PATCH", `/expenses/${expense.id}`, {
+                description: description.value,
+                amount: Number(amount.value),
+            });
+            await load();
+        } catch (error) {
+            showError(error);
+        }
+    });
+    button(actions, "Cancel", () => renderRow(row, expense));
+    description.focus();
+}
+
+addForm.addEventListener("submit", async event => {
+    event.preventDefault();
+    const description = document.querySelector("input[form=add][name=description]");
+    const amount = document.querySelector("input[form=add][name=amount]");
+    try {
+        await request("POST", "/expenses", {
+            description: description.value,
+            amount: Number(amount.value),
+        });
+        addForm.reset();
+        await load();
+    } catch (error) {
+        showError(error);
+    }
+});
+
+filters.addEventListener("change", load);
+filters.elements.search.addEventListener("input", render);
+filters.addEventListener("reset", () => setTimeout(load));
+
+load();